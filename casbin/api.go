@@ -0,0 +1,451 @@
+package casbin
+
+import (
+	"strconv"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/x/errors"
+)
+
+// PolicyAPI is a Party configurator which exposes the policy management REST API
+// of a Casbin middleware. The API is protected by its own Casbin middleware, the "guard",
+// which is usually based on a separate model and policy, e.g.:
+//
+//	p, admin, /admin/casbin/*, (GET)|(POST)|(PUT)|(DELETE)
+//
+// The API registers the following routes:
+// - GET /policies - lists the policy rules, see below.
+// - POST /policies - adds policy rules, e.g. {"ptype": "p", "rules": [["alice", "/data", "GET"]]}.
+// - PUT /policies - updates a policy rule, e.g. {"ptype": "p", "old": ["alice", "/data", "GET"], "new": ["alice", "/data", "POST"]}.
+// - DELETE /policies - removes policy rules, same payload as POST.
+// - GET /groupings - lists the grouping (role) rules.
+// - POST /groupings - adds grouping rules, e.g. {"ptype": "g", "rules": [["alice", "admin"]]}.
+// - DELETE /groupings - removes grouping rules, same payload as POST.
+// - GET /users/{user}/roles - lists the roles of a user.
+// - GET /roles/{role}/users - lists the users of a role.
+// - PUT /users/{user}/roles/{role} - assigns a role to a user.
+// - DELETE /users/{user}/roles/{role} - unassigns a role from a user.
+// - POST /check - reports whether a request would be allowed, e.g. {"request": ["alice", "/data", "GET"]}.
+//
+// The list routes accept the "ptype" (defaults to "p" and "g"), "offset" and "limit" URL parameters
+// and filter the rules by the "v0", "v1", ... URL parameters, e.g. ?v0=alice.
+// The role routes accept the "domain" URL parameter for models with domains.
+//
+// Usage:
+//
+//	app.PartyConfigure("/admin/casbin", casbin.NewPolicyAPI(casbinMiddleware, adminMiddleware))
+type PolicyAPI struct {
+	casbin *Casbin
+	guard  *Casbin
+
+	// DefaultLimit is the number of rules returned by the list routes
+	// when the "limit" URL parameter is missing. Defaults to 100.
+	DefaultLimit int
+	// MaxLimit is the maximum number of rules returned by the list routes. Defaults to 1000.
+	MaxLimit int
+}
+
+// NewPolicyAPI returns a new PolicyAPI which manages the policy of "c"
+// and is protected by the "guard" Casbin middleware.
+// It panics if the guard is nil.
+//
+// Read the type's documentation for more information.
+func NewPolicyAPI(c *Casbin, guard *Casbin) *PolicyAPI {
+	if guard == nil {
+		panic("casbin: policy API requires a guard")
+	}
+
+	return &PolicyAPI{
+		casbin:       c,
+		guard:        guard,
+		DefaultLimit: 100,
+		MaxLimit:     1000,
+	}
+}
+
+// Configure registers the API's routes.
+// It is called automatically by the Iris API Builder when registered to the Iris Application.
+func (api *PolicyAPI) Configure(r iris.Party) {
+	r.Use(api.guard.ServeHTTP)
+
+	r.Get("/policies", api.listRules("p"))
+	r.Post("/policies", api.addRules("p"))
+	r.Put("/policies", api.updatePolicy)
+	r.Delete("/policies", api.removeRules("p"))
+
+	r.Get("/groupings", api.listRules("g"))
+	r.Post("/groupings", api.addRules("g"))
+	r.Delete("/groupings", api.removeRules("g"))
+
+	r.Get("/users/{user}/roles", api.getRoles)
+	r.Get("/roles/{role}/users", api.getUsers)
+	r.Put("/users/{user}/roles/{role}", api.addRole)
+	r.Delete("/users/{user}/roles/{role}", api.deleteRole)
+
+	r.Post("/check", api.check)
+}
+
+// RulesPage is the response of the list routes of the PolicyAPI.
+type RulesPage struct {
+	PType  string     `json:"ptype"`
+	Rules  [][]string `json:"rules"`
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+// CheckResult is the response of the check route of the PolicyAPI.
+type CheckResult struct {
+	Allowed bool     `json:"allowed"`
+	Rule    []string `json:"rule,omitempty"`
+}
+
+type rulesPayload struct {
+	PType string     `json:"ptype"`
+	Rules [][]string `json:"rules"`
+}
+
+type updatePayload struct {
+	PType string   `json:"ptype"`
+	Old   []string `json:"old"`
+	New   []string `json:"new"`
+}
+
+type checkPayload struct {
+	Request []string `json:"request"`
+}
+
+// ptype returns the policy type of the "sec" section ("p" or "g")
+// and reports whether it is defined by the model, the error is sent to the client.
+func (api *PolicyAPI) ptype(ctx iris.Context, sec, ptype string) (string, int, bool) {
+	if ptype == "" {
+		ptype = sec
+	}
+
+	ast, ok := api.casbin.enforcer.GetModel()[sec][ptype]
+	if !ok {
+		errors.InvalidArgument.Message(ctx, "unknown %s type %q", sectionName(sec), ptype)
+		return "", 0, false
+	}
+
+	return ptype, len(ast.Tokens), true
+}
+
+// validateRules reports whether the rules match the "tokens" size and have no empty values,
+// the error is sent to the client.
+func validateRules(ctx iris.Context, tokens int, rules ...[]string) bool {
+	if len(rules) == 0 {
+		errors.InvalidArgument.Message(ctx, "no rules")
+		return false
+	}
+
+	for i, rule := range rules {
+		if len(rule) != tokens {
+			errors.InvalidArgument.Message(ctx, "rule %d: expected %d values but got %d", i, tokens, len(rule))
+			return false
+		}
+
+		for j, v := range rule {
+			if v == "" {
+				errors.InvalidArgument.Message(ctx, "rule %d: empty value at %d", i, j)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func sectionName(sec string) string {
+	if sec == "g" {
+		return "grouping"
+	}
+
+	return "policy"
+}
+
+func (api *PolicyAPI) listRules(sec string) iris.Handler {
+	return func(ctx iris.Context) {
+		ptype, tokens, ok := api.ptype(ctx, sec, ctx.URLParam("ptype"))
+		if !ok {
+			return
+		}
+
+		offset, limit, ok := api.page(ctx)
+		if !ok {
+			return
+		}
+
+		var filter []string
+		for i := 0; i < tokens; i++ {
+			filter = append(filter, ctx.URLParam("v"+strconv.Itoa(i)))
+		}
+		for len(filter) > 0 && filter[len(filter)-1] == "" {
+			filter = filter[:len(filter)-1]
+		}
+
+		api.casbin.mu.RLock()
+		var (
+			rules [][]string
+			err   error
+		)
+		if sec == "g" {
+			rules, err = api.casbin.enforcer.GetFilteredNamedGroupingPolicy(ptype, 0, filter...)
+		} else {
+			rules, err = api.casbin.enforcer.GetFilteredNamedPolicy(ptype, 0, filter...)
+		}
+		api.casbin.mu.RUnlock()
+		if err != nil {
+			errors.Internal.LogErr(ctx, err)
+			return
+		}
+
+		page := RulesPage{PType: ptype, Rules: [][]string{}, Total: len(rules), Offset: offset, Limit: limit}
+		if offset < len(rules) {
+			page.Rules = rules[offset:min(offset+limit, len(rules))]
+		}
+
+		ctx.JSON(page)
+	}
+}
+
+// page returns the "offset" and "limit" URL parameters, the error is sent to the client.
+func (api *PolicyAPI) page(ctx iris.Context) (int, int, bool) {
+	offset := 0
+	if ctx.URLParamExists("offset") {
+		v, err := ctx.URLParamInt("offset")
+		if err != nil || v < 0 {
+			errors.InvalidArgument.Message(ctx, "invalid offset")
+			return 0, 0, false
+		}
+
+		offset = v
+	}
+
+	limit := api.DefaultLimit
+	if ctx.URLParamExists("limit") {
+		v, err := ctx.URLParamInt("limit")
+		if err != nil || v <= 0 {
+			errors.InvalidArgument.Message(ctx, "invalid limit")
+			return 0, 0, false
+		}
+
+		limit = v
+	}
+
+	if api.MaxLimit > 0 && limit > api.MaxLimit {
+		limit = api.MaxLimit
+	}
+
+	return offset, limit, true
+}
+
+func (api *PolicyAPI) readRules(ctx iris.Context, sec string) (string, [][]string, bool) {
+	var payload rulesPayload
+	if err := ctx.ReadJSON(&payload); err != nil {
+		errors.HandleError(ctx, err)
+		return "", nil, false
+	}
+
+	ptype, tokens, ok := api.ptype(ctx, sec, payload.PType)
+	if !ok || !validateRules(ctx, tokens, payload.Rules...) {
+		return "", nil, false
+	}
+
+	return ptype, payload.Rules, true
+}
+
+func (api *PolicyAPI) addRules(sec string) iris.Handler {
+	return func(ctx iris.Context) {
+		ptype, rules, ok := api.readRules(ctx, sec)
+		if !ok {
+			return
+		}
+
+		ok, err := api.casbin.changePolicy(PolicyChange{Op: PolicyAdd, Sec: sec, PType: ptype, Rules: rules})
+		if err != nil {
+			errors.Internal.LogErr(ctx, err)
+			return
+		}
+
+		if !ok {
+			errors.AlreadyExists.Message(ctx, "%s rules already exist", sectionName(sec))
+			return
+		}
+
+		ctx.StatusCode(iris.StatusCreated)
+	}
+}
+
+func (api *PolicyAPI) removeRules(sec string) iris.Handler {
+	return func(ctx iris.Context) {
+		ptype, rules, ok := api.readRules(ctx, sec)
+		if !ok {
+			return
+		}
+
+		ok, err := api.casbin.changePolicy(PolicyChange{Op: PolicyRemove, Sec: sec, PType: ptype, Rules: rules})
+		if err != nil {
+			errors.Internal.LogErr(ctx, err)
+			return
+		}
+
+		if !ok {
+			errors.NotFound.Message(ctx, "%s rules not found", sectionName(sec))
+			return
+		}
+
+		ctx.StatusCode(iris.StatusNoContent)
+	}
+}
+
+func (api *PolicyAPI) updatePolicy(ctx iris.Context) {
+	var payload updatePayload
+	if err := ctx.ReadJSON(&payload); err != nil {
+		errors.HandleError(ctx, err)
+		return
+	}
+
+	ptype, tokens, ok := api.ptype(ctx, "p", payload.PType)
+	if !ok || !validateRules(ctx, tokens, payload.Old, payload.New) {
+		return
+	}
+
+	ok, err := api.casbin.changePolicy(PolicyChange{
+		Op:       PolicyUpdate,
+		Sec:      "p",
+		PType:    ptype,
+		Rules:    [][]string{payload.Old},
+		NewRules: [][]string{payload.New},
+	})
+	if err != nil {
+		errors.Internal.LogErr(ctx, err)
+		return
+	}
+
+	if !ok {
+		errors.NotFound.Message(ctx, "policy rule not found")
+		return
+	}
+
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+func domain(ctx iris.Context) []string {
+	if d := ctx.URLParam("domain"); d != "" {
+		return []string{d}
+	}
+
+	return nil
+}
+
+func (api *PolicyAPI) getRoles(ctx iris.Context) {
+	api.casbin.mu.RLock()
+	roles, err := api.casbin.enforcer.GetRolesForUser(ctx.Params().Get("user"), domain(ctx)...)
+	api.casbin.mu.RUnlock()
+	api.writeNames(ctx, roles, err)
+}
+
+func (api *PolicyAPI) getUsers(ctx iris.Context) {
+	api.casbin.mu.RLock()
+	users, err := api.casbin.enforcer.GetUsersForRole(ctx.Params().Get("role"), domain(ctx)...)
+	api.casbin.mu.RUnlock()
+	api.writeNames(ctx, users, err)
+}
+
+func (api *PolicyAPI) writeNames(ctx iris.Context, names []string, err error) {
+	if err != nil {
+		errors.Internal.LogErr(ctx, err)
+		return
+	}
+
+	if names == nil {
+		names = []string{}
+	}
+
+	ctx.JSON(names)
+}
+
+// roleRule returns the grouping rule of the user, role and optional domain of the request,
+// it reports false, after sending the error to the client, if it does not match the "g" type.
+func (api *PolicyAPI) roleRule(ctx iris.Context) ([]string, bool) {
+	_, tokens, ok := api.ptype(ctx, "g", "")
+	if !ok {
+		return nil, false
+	}
+
+	rule := append([]string{ctx.Params().Get("user"), ctx.Params().Get("role")}, domain(ctx)...)
+	if !validateRules(ctx, tokens, rule) {
+		return nil, false
+	}
+
+	return rule, true
+}
+
+func (api *PolicyAPI) addRole(ctx iris.Context) {
+	rule, ok := api.roleRule(ctx)
+	if !ok {
+		return
+	}
+
+	added, err := api.casbin.changePolicy(PolicyChange{Op: PolicyAdd, Sec: "g", PType: "g", Rules: [][]string{rule}})
+	if err != nil {
+		errors.Internal.LogErr(ctx, err)
+		return
+	}
+
+	if !added {
+		errors.AlreadyExists.Message(ctx, "role already assigned")
+		return
+	}
+
+	ctx.StatusCode(iris.StatusCreated)
+}
+
+func (api *PolicyAPI) deleteRole(ctx iris.Context) {
+	rule, ok := api.roleRule(ctx)
+	if !ok {
+		return
+	}
+
+	ok, err := api.casbin.changePolicy(PolicyChange{Op: PolicyRemove, Sec: "g", PType: "g", Rules: [][]string{rule}})
+	if err != nil {
+		errors.Internal.LogErr(ctx, err)
+		return
+	}
+
+	if !ok {
+		errors.NotFound.Message(ctx, "role not assigned")
+		return
+	}
+
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+func (api *PolicyAPI) check(ctx iris.Context) {
+	var payload checkPayload
+	if err := ctx.ReadJSON(&payload); err != nil {
+		errors.HandleError(ctx, err)
+		return
+	}
+
+	if ast, ok := api.casbin.enforcer.GetModel()["r"]["r"]; ok && len(payload.Request) != len(ast.Tokens) {
+		errors.InvalidArgument.Message(ctx, "expected %d request values but got %d", len(ast.Tokens), len(payload.Request))
+		return
+	}
+
+	request := make([]interface{}, len(payload.Request))
+	for i, v := range payload.Request {
+		request[i] = v
+	}
+
+	api.casbin.mu.RLock()
+	allowed, rule, err := api.casbin.enforcer.EnforceEx(request...)
+	api.casbin.mu.RUnlock()
+	if err != nil {
+		errors.InvalidArgument.Err(ctx, err)
+		return
+	}
+
+	ctx.JSON(CheckResult{Allowed: allowed, Rule: rule})
+}
@@ -0,0 +1,140 @@
+package casbin
+
+import (
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+)
+
+const rbacModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch(r.obj, p.obj) && regexMatch(r.act, p.act)
+`
+
+func TestPolicyAPI(t *testing.T) {
+	c := New(newTestEnforcer(t, rbacModel, []string{"reader", "/data/*", "GET"}))
+	guard := New(newTestEnforcer(t, rbacModel, []string{"admin", "/admin/*", "(GET)|(POST)|(PUT)|(DELETE)"}))
+
+	app := iris.New()
+	app.Use(subjectFromHeader)
+	app.PartyConfigure("/admin", NewPolicyAPI(c, guard))
+	app.Get("/data/{id}", c.ServeHTTP, func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+
+	e := httptest.New(t, app)
+	e.GET("/admin/policies").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+
+	admin := func(method, path string) *httptest.Request {
+		return e.Request(method, path).WithHeader("X-User", "admin")
+	}
+
+	admin("GET", "/admin/policies").Expect().Status(httptest.StatusOK).JSON().IsEqual(RulesPage{
+		PType: "p", Rules: [][]string{{"reader", "/data/*", "GET"}}, Total: 1, Limit: 100,
+	})
+
+	// validation.
+	admin("POST", "/admin/policies").WithJSON(rulesPayload{Rules: [][]string{{"writer", "/data/*"}}}).
+		Expect().Status(httptest.StatusBadRequest)
+	admin("POST", "/admin/policies").WithJSON(rulesPayload{PType: "p2", Rules: [][]string{{"writer", "/data/*", "POST"}}}).
+		Expect().Status(httptest.StatusBadRequest)
+
+	admin("POST", "/admin/policies").WithJSON(rulesPayload{Rules: [][]string{{"writer", "/data/*", "POST"}, {"writer", "/data/*", "GET"}}}).
+		Expect().Status(httptest.StatusCreated)
+	admin("POST", "/admin/policies").WithJSON(rulesPayload{Rules: [][]string{{"writer", "/data/*", "POST"}}}).
+		Expect().Status(httptest.StatusConflict)
+
+	// filter and pagination.
+	admin("GET", "/admin/policies").WithQuery("v0", "writer").WithQuery("limit", 1).WithQuery("offset", 1).
+		Expect().Status(httptest.StatusOK).JSON().IsEqual(RulesPage{
+		PType: "p", Rules: [][]string{{"writer", "/data/*", "GET"}}, Total: 2, Offset: 1, Limit: 1,
+	})
+	admin("GET", "/admin/policies").WithQuery("limit", -1).Expect().Status(httptest.StatusBadRequest)
+
+	admin("PUT", "/admin/policies").WithJSON(updatePayload{Old: []string{"writer", "/data/*", "GET"}, New: []string{"writer", "/data/*", "PUT"}}).
+		Expect().Status(httptest.StatusNoContent)
+	admin("DELETE", "/admin/policies").WithJSON(rulesPayload{Rules: [][]string{{"writer", "/data/*", "GET"}}}).
+		Expect().Status(httptest.StatusNotFound)
+	admin("DELETE", "/admin/policies").WithJSON(rulesPayload{Rules: [][]string{{"writer", "/data/*", "PUT"}}}).
+		Expect().Status(httptest.StatusNoContent)
+
+	// roles.
+	e.GET("/data/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+	admin("PUT", "/admin/users/alice/roles/reader").Expect().Status(httptest.StatusCreated)
+	admin("PUT", "/admin/users/alice/roles/reader").Expect().Status(httptest.StatusConflict)
+	e.GET("/data/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+
+	admin("GET", "/admin/users/alice/roles").Expect().Status(httptest.StatusOK).JSON().IsEqual([]string{"reader"})
+	admin("GET", "/admin/roles/reader/users").Expect().Status(httptest.StatusOK).JSON().IsEqual([]string{"alice"})
+	admin("POST", "/admin/groupings").WithJSON(rulesPayload{Rules: [][]string{{"bob", "writer"}}}).
+		Expect().Status(httptest.StatusCreated)
+	admin("GET", "/admin/groupings").Expect().Status(httptest.StatusOK).JSON().Object().Value("total").IsEqual(2)
+
+	// dry-run.
+	admin("POST", "/admin/check").WithJSON(checkPayload{Request: []string{"alice", "/data/1", "GET"}}).
+		Expect().Status(httptest.StatusOK).JSON().IsEqual(CheckResult{Allowed: true, Rule: []string{"reader", "/data/*", "GET"}})
+	admin("POST", "/admin/check").WithJSON(checkPayload{Request: []string{"alice", "/data/1", "DELETE"}}).
+		Expect().Status(httptest.StatusOK).JSON().IsEqual(CheckResult{})
+	admin("POST", "/admin/check").WithJSON(checkPayload{Request: []string{"alice"}}).
+		Expect().Status(httptest.StatusBadRequest)
+
+	admin("DELETE", "/admin/users/alice/roles/reader").Expect().Status(httptest.StatusNoContent)
+	admin("DELETE", "/admin/users/alice/roles/reader").Expect().Status(httptest.StatusNotFound)
+	e.GET("/data/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+}
+
+const rbacDomainsModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && keyMatch(r.obj, p.obj) && r.act == p.act
+`
+
+func TestPolicyAPIRolesDomains(t *testing.T) {
+	c := New(newTestEnforcer(t, rbacDomainsModel, []string{"reader", "tenant1", "/data/*", "GET"}))
+	guard := New(newTestEnforcer(t, rbacModel, []string{"admin", "/admin/*", "(GET)|(POST)|(PUT)|(DELETE)"}))
+
+	app := iris.New()
+	app.Use(subjectFromHeader)
+	app.PartyConfigure("/admin", NewPolicyAPI(c, guard))
+
+	e := httptest.New(t, app)
+	admin := func(method, path string) *httptest.Request {
+		return e.Request(method, path).WithHeader("X-User", "admin")
+	}
+
+	// the "g" type requires a domain.
+	admin("PUT", "/admin/users/alice/roles/reader").Expect().Status(httptest.StatusBadRequest)
+	admin("DELETE", "/admin/users/alice/roles/reader").Expect().Status(httptest.StatusBadRequest)
+
+	admin("PUT", "/admin/users/alice/roles/reader").WithQuery("domain", "tenant1").Expect().Status(httptest.StatusCreated)
+	mustOK(t)(c.enforcer.Enforce("alice", "tenant1", "/data/1", "GET"))
+
+	admin("DELETE", "/admin/users/alice/roles/reader").WithQuery("domain", "tenant1").Expect().Status(httptest.StatusNoContent)
+	if ok, _ := c.enforcer.Enforce("alice", "tenant1", "/data/1", "GET"); ok {
+		t.Fatal("expected the role to be removed")
+	}
+}
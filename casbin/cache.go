@@ -0,0 +1,155 @@
+package casbin
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the maximum number of decisions of a DecisionCache with a zero size.
+const DefaultCacheSize = 10000
+
+// DecisionCache holds the enforcer's results by the enforcer's arguments, see the Casbin.Cache field.
+// The least recently used decisions are evicted when the cache is full.
+// Requests with arguments other than strings, booleans and integers, e.g. ABAC objects, are not cached.
+type DecisionCache struct {
+	size int
+	ttl  time.Duration
+	// now returns the current time, it is replaced in the tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// generation is increased on each Clear call, so decisions made before that are not stored.
+	generation uint64
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type cacheEntry struct {
+	key     string
+	allowed bool
+	rule    []string
+	expires time.Time
+}
+
+// NewDecisionCache returns a new DecisionCache of maximum "size" decisions,
+// which expire after the "ttl" duration.
+// A zero size defaults to DefaultCacheSize and a zero ttl disables the expiration.
+//
+// Usage:
+//
+//	casbinMiddleware.Cache = casbin.NewDecisionCache(0, time.Minute)
+func NewDecisionCache(size int, ttl time.Duration) *DecisionCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	return &DecisionCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Len returns the number of the cached decisions, including the expired ones which are not evicted yet.
+func (c *DecisionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Clear removes all the cached decisions. It is called automatically on the policy changes
+// made through the Casbin middleware, e.g. by the PolicyAPI, a Watcher or the Reload method.
+// Call it after changing the enforcer's policy directly.
+func (c *DecisionCache) Clear() {
+	c.mu.Lock()
+	c.generation++
+	clear(c.entries)
+	c.lru.Init()
+	c.mu.Unlock()
+}
+
+// get returns the cached decision of the "key" and the current generation,
+// which should be passed to the put method on a miss.
+func (c *DecisionCache) get(key string) (*cacheEntry, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, c.generation
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if c.ttl > 0 && c.now().After(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, c.generation
+	}
+
+	c.lru.MoveToFront(el)
+	return entry, c.generation
+}
+
+// put stores a decision unless the cache was cleared since the "generation",
+// as the decision may be made by an outdated policy.
+func (c *DecisionCache) put(key string, generation uint64, allowed bool, rule []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	entry := &cacheEntry{key: key, allowed: allowed, rule: rule}
+	if c.ttl > 0 {
+		entry.expires = c.now().Add(c.ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey returns the cache key of the enforcer's arguments,
+// it reports false if an argument cannot be part of a key.
+func cacheKey(args []interface{}) (string, bool) {
+	var b strings.Builder
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = "s" + v
+		case bool:
+			s = "b" + strconv.FormatBool(v)
+		case int:
+			s = "i" + strconv.Itoa(v)
+		case int64:
+			s = "i" + strconv.FormatInt(v, 10)
+		case uint64:
+			s = "u" + strconv.FormatUint(v, 10)
+		default:
+			return "", false
+		}
+
+		// length-prefixed, so "a,b" and "a", "b" differ.
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+	}
+
+	return b.String(), true
+}
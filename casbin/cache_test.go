@@ -0,0 +1,147 @@
+package casbin
+
+import (
+	"fmt"
+	"net/http"
+	nethttptest "net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+)
+
+func TestDecisionCache(t *testing.T) {
+	now := time.Now()
+	cache := NewDecisionCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	_, gen := cache.get("a")
+	cache.put("a", gen, true, nil)
+	cache.put("b", gen, false, nil)
+	if entry, _ := cache.get("a"); entry == nil || !entry.allowed {
+		t.Fatal("expected a cached allowed decision")
+	}
+
+	// "b" is the least recently used.
+	cache.put("c", gen, true, nil)
+	if entry, _ := cache.get("b"); entry != nil {
+		t.Fatal("expected an evicted decision")
+	}
+	if n := cache.Len(); n != 2 {
+		t.Fatalf("expected 2 decisions but got %d", n)
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if entry, _ := cache.get("a"); entry != nil {
+		t.Fatal("expected an expired decision")
+	}
+
+	// decisions made before a Clear call are not stored.
+	_, gen = cache.get("d")
+	cache.Clear()
+	cache.put("d", gen, true, nil)
+	if n := cache.Len(); n != 0 {
+		t.Fatalf("expected an empty cache but got %d decisions", n)
+	}
+
+	if _, ok := cacheKey([]interface{}{"alice", struct{ Owner string }{"alice"}}); ok {
+		t.Fatal("expected a non-cacheable request")
+	}
+
+	k1, _ := cacheKey([]interface{}{"a,b", "c"})
+	k2, _ := cacheKey([]interface{}{"a", "b,c"})
+	if k1 == k2 {
+		t.Fatalf("expected different keys but got %q", k1)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := New(newTestEnforcer(t, rbacModel, []string{"alice", "/data", "GET"}))
+	c.Cache = NewDecisionCache(0, 0)
+
+	app := iris.New()
+	app.UseRouter(subjectFromHeader, c.ServeHTTP)
+	app.Get("/data", func(ctx iris.Context) {})
+
+	e := httptest.New(t, app)
+	e.GET("/data").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+	e.GET("/data").WithHeader("X-User", "bob").Expect().Status(httptest.StatusForbidden)
+	if n := c.Cache.Len(); n != 2 {
+		t.Fatalf("expected 2 cached decisions but got %d", n)
+	}
+
+	// changes through the middleware clear the cache.
+	if err := c.applyChange(PolicyChange{Op: PolicyAdd, Sec: "p", PType: "p", Rules: [][]string{{"bob", "/data", "GET"}}}); err != nil {
+		t.Fatal(err)
+	}
+	e.GET("/data").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK)
+
+	// direct changes require a Clear call.
+	if _, err := c.enforcer.RemovePolicy("bob", "/data", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	e.GET("/data").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK)
+	c.Cache.Clear()
+	e.GET("/data").WithHeader("X-User", "bob").Expect().Status(httptest.StatusForbidden)
+}
+
+// newBenchmarkApp returns an application protected by a policy of many users and roles,
+// where each user inherits its permissions through a chain of roles,
+// up to the default role manager's maximum hierarchy level.
+func newBenchmarkApp(b *testing.B, cache *DecisionCache) *iris.Application {
+	e := newTestEnforcer(b, rbacModel)
+	const roles, users = 100, 1000
+	for i := 0; i < roles; i++ {
+		if _, err := e.AddPolicy(fmt.Sprintf("role%d", i), fmt.Sprintf("/data%d/*", i), "GET"); err != nil {
+			b.Fatal(err)
+		}
+		if i%10 > 0 {
+			if _, err := e.AddGroupingPolicy(fmt.Sprintf("role%d", i), fmt.Sprintf("role%d", i-1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	for i := 0; i < users; i++ {
+		if _, err := e.AddGroupingPolicy(fmt.Sprintf("user%d", i), fmt.Sprintf("role%d", i%roles/10*10+9)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	c := New(e)
+	c.Cache = cache
+
+	app := iris.New()
+	app.UseRouter(subjectFromHeader, c.ServeHTTP)
+	app.Get("/data0/{id}", func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		b.Fatal(err)
+	}
+
+	return app
+}
+
+func benchmarkServeHTTP(b *testing.B, cache *DecisionCache) {
+	app := newBenchmarkApp(b, cache)
+
+	req := nethttptest.NewRequest(http.MethodGet, "/data0/1", nil)
+	req.Header.Set("X-User", "user1")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := nethttptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("expected status 200 but got %d", w.Code)
+		}
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	benchmarkServeHTTP(b, nil)
+}
+
+func BenchmarkServeHTTPCached(b *testing.B) {
+	benchmarkServeHTTP(b, NewDecisionCache(0, time.Minute))
+}
@@ -1,6 +1,10 @@
 package casbin
 
 import (
+	"errors"
+	"strings"
+	"sync"
+
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/context"
 
@@ -14,6 +18,12 @@ func init() {
 // Casbin is the auth service which contains the casbin enforcer.
 type Casbin struct {
 	enforcer *casbin.Enforcer
+	// mu guards the enforcer's policy against the PolicyAPI's changes.
+	mu sync.RWMutex
+	// changeMu serializes the PolicyAPI's changes, which persist and publish them without holding mu.
+	changeMu sync.Mutex
+	// watcher is set by the Watch method, under mu.
+	watcher *watcherBridge
 	// SubjectExtractor is used to extract the
 	// current request's subject for the casbin role enforcer.
 	// Defaults to the `Subject` package-level function which
@@ -25,13 +35,54 @@ type Casbin struct {
 	// when the role checks fail.
 	// Defaults to a handler which sends a status forbidden (403) status code.
 	UnauthorizedHandler iris.Handler
+
+	// RequestBuilder builds the enforcer's arguments of the current request,
+	// e.g. for models with domains or attributes, see the Args function.
+	// Defaults to the `DefaultRequestBuilder` package-level function which
+	// builds the `{subject, path, method}` arguments.
+	RequestBuilder RequestBuilder
+
+	// ErrorHandler sets a custom handler to be executed
+	// when the enforcer or the RequestBuilder fails, e.g. on a broken model or adapter.
+	// Defaults to the `DefaultErrorHandler` package-level function.
+	ErrorHandler func(ctx iris.Context, err error)
+
+	// Explain, if true, sends the policy rule which decided the request
+	// through the "X-Casbin-Rule" response header, for debugging.
+	// The rule is also available through the GetDecision package-level function.
+	// Defaults to false.
+	Explain bool
+
+	// Observers are notified about each decision, see the LogDecisions package-level function.
+	Observers []Observer
+
+	// Cache, if not nil, holds the enforcer's results, see the NewDecisionCache package-level function.
+	// It is cleared on the policy changes made through the PolicyAPI, a Watcher or the Reload method.
+	// Defaults to nil.
+	Cache *DecisionCache
+
+	// permissions holds the route-level permissions, see the Require method.
+	permissions routePermissions
+}
+
+// ExplainHeader is the response header which holds the policy rule
+// which decided the request, see the Casbin.Explain field.
+const ExplainHeader = "X-Casbin-Rule"
+
+// DefaultErrorHandler is the default ErrorHandler of the Casbin middleware.
+// It logs the error and sends a status internal server error (500) status code,
+// the error is not rendered to the client.
+func DefaultErrorHandler(ctx iris.Context, err error) {
+	ctx.Application().Logger().Errorf("casbin: %s %s: %v", ctx.Method(), ctx.Path(), err)
+	ctx.StopWithStatus(iris.StatusInternalServerError)
 }
 
 // New returns the Casbin middleware based on the given casbin.Enforcer instance.
 // The authorization determines a request based on `{subject, object, action}`.
 // Please refer to: https://github.com/casbin/casbin to understand how it works first.
 //
-// The object is the current request's path and the action is the current request's method.
+// The object is the current request's path and the action is the current request's method,
+// see the RequestBuilder field to customize the enforcer's arguments.
 // The subject that casbin requires is extracted by:
 //   - SubjectExtractor
 //   - casbin.Subject
@@ -47,6 +98,8 @@ func New(e *casbin.Enforcer) *Casbin {
 		UnauthorizedHandler: func(ctx iris.Context) {
 			ctx.StopWithStatus(iris.StatusForbidden)
 		},
+		RequestBuilder: DefaultRequestBuilder,
+		ErrorHandler:   DefaultErrorHandler,
 	}
 }
 
@@ -63,14 +116,26 @@ func NewEnforcer(modelFile, policyFile string) (*Casbin, error) {
 }
 
 // ServeHTTP is the iris compatible casbin handler which should be passed to specific routes or parties.
-// Responds with Status Forbidden on unauthorized clients.
+// Responds with Status Forbidden on unauthorized clients
+// and calls the ErrorHandler on enforcement errors.
 // Usage:
 // - app.Use(authMiddleware)
 // - app.Use(casbinMiddleware.ServeHTTP) OR
 // - app.UseRouter(casbinMiddleware.ServeHTTP) OR per route:
 // - app.Get("/dataset1/resource1", casbinMiddleware.ServeHTTP, myHandler)
 func (c *Casbin) ServeHTTP(ctx iris.Context) {
-	if !c.Check(ctx) {
+	d := c.Decide(ctx)
+	if d.Err != nil {
+		errorHandler := c.ErrorHandler
+		if errorHandler == nil {
+			errorHandler = DefaultErrorHandler
+		}
+
+		errorHandler(ctx, d.Err)
+		return
+	}
+
+	if !d.Allowed {
 		c.UnauthorizedHandler(ctx)
 		return
 	}
@@ -80,20 +145,122 @@ func (c *Casbin) ServeHTTP(ctx iris.Context) {
 
 // Check checks the username, request's method and path and
 // returns true if permission grandted otherwise false.
+// Enforcement errors are reported as false, use Decide to tell them apart.
 //
 // It's an Iris Filter.
 // Usage:
 // - inside a handler
 // - using the iris.NewConditionalHandler
 func (c *Casbin) Check(ctx iris.Context) bool {
-	ok, _ := c.Enforce(ctx, c.SubjectExtractor(ctx))
-	return ok
+	return c.Decide(ctx).Allowed
+}
+
+// Decide checks the current request, like Check, and returns the whole Decision,
+// including the enforcement error and the policy rule which decided the request.
+// The decision is also available to the next handlers through the GetDecision package-level function
+// and it is sent to the Observers.
+func (c *Casbin) Decide(ctx iris.Context) Decision {
+	subject := c.SubjectExtractor(ctx)
+	args, err := c.requestArgs(ctx, subject)
+
+	d := newDecision(subject, args)
+	if err != nil {
+		// routes without a declared permission are denied, not failed.
+		if !errors.Is(err, ErrNoPermission) {
+			d.Err = err
+		}
+	} else {
+		d.Allowed, d.Rule, d.Err = c.enforce(args)
+	}
+
+	if d.Err != nil {
+		d.Allowed = false
+	}
+
+	if c.Explain && len(d.Rule) > 0 {
+		ctx.Header(ExplainHeader, strings.Join(d.Rule, ", "))
+	}
+
+	ctx.Values().Set(decisionContextKey, d)
+	for _, o := range c.Observers {
+		o.ObserveDecision(ctx, d)
+	}
+
+	return d
+}
+
+// enforce calls the enforcer, or returns the cached result of the "args".
+func (c *Casbin) enforce(args []interface{}) (allowed bool, rule []string, err error) {
+	explain := c.Explain || len(c.Observers) > 0
+
+	cache := c.Cache
+	key, cacheable := "", false
+	var generation uint64
+	if cache != nil {
+		if key, cacheable = cacheKey(args); cacheable {
+			var entry *cacheEntry
+			if entry, generation = cache.get(key); entry != nil {
+				return entry.allowed, entry.rule, nil
+			}
+		}
+	}
+
+	c.mu.RLock()
+	if explain {
+		allowed, rule, err = c.enforcer.EnforceEx(args...)
+	} else {
+		allowed, err = c.enforcer.Enforce(args...)
+	}
+	c.mu.RUnlock()
+
+	if cacheable && err == nil {
+		cache.put(key, generation, allowed, rule)
+	}
+
+	return
+}
+
+// lockPolicy locks the enforcer's policy for changes.
+func (c *Casbin) lockPolicy() {
+	c.mu.Lock()
+}
+
+// unlockPolicy clears the cached decisions, as the policy may be changed, and unlocks the enforcer's policy.
+func (c *Casbin) unlockPolicy() {
+	if c.Cache != nil {
+		c.Cache.Clear()
+	}
+
+	c.mu.Unlock()
 }
 
 // Enforce accepts the Context's path and method and a subject/role/username
 // and reports whether the specific "subject" has access to the current request.
+// The enforcer's arguments are built by the RequestBuilder.
 func (c *Casbin) Enforce(ctx iris.Context, subject string) (bool, error) {
-	return c.enforcer.Enforce(subject, ctx.Path(), ctx.Method())
+	args, err := c.requestArgs(ctx, subject)
+	if err != nil {
+		if errors.Is(err, ErrNoPermission) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	allowed, _, err := c.enforce(args)
+	return allowed, err
+}
+
+func (c *Casbin) requestArgs(ctx iris.Context, subject string) ([]interface{}, error) {
+	if perm, ok := c.permission(ctx); ok {
+		ctx.Values().Set(permissionContextKey, perm)
+	}
+
+	if c.RequestBuilder == nil {
+		return DefaultRequestBuilder(ctx, subject)
+	}
+
+	return c.RequestBuilder(ctx, subject)
 }
 
 const subjectContextKey = "iris.contrib.casbin.subject"
@@ -0,0 +1,207 @@
+package casbin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+)
+
+func newTestEnforcer(t testing.TB, text string, policies ...[]string) *casbin.Enforcer {
+	t.Helper()
+
+	m, err := model.NewModelFromString(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range policies {
+		if _, err = e.AddPolicy(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return e
+}
+
+func subjectFromHeader(ctx iris.Context) {
+	SetSubject(ctx, ctx.GetHeader("X-User"))
+	ctx.Next()
+}
+
+const domainsModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+`
+
+func TestRequestBuilderDomains(t *testing.T) {
+	c := New(newTestEnforcer(t, domainsModel,
+		[]string{"alice", "tenant1", "/{tenant}/users/{id:uint64}", "GET"},
+		[]string{"bob", "tenant2", "/{tenant}/users/{id:uint64}", "GET"},
+	))
+	c.RequestBuilder = Args(SubjectArg(), ParamArg("tenant"), RouteArg(), MethodArg())
+
+	app := iris.New()
+	app.Get("/{tenant}/users/{id:uint64}", subjectFromHeader, c.ServeHTTP, func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+
+	e := httptest.New(t, app)
+	e.GET("/tenant1/users/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+	e.GET("/tenant1/users/42").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+	e.GET("/tenant2/users/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+	e.GET("/tenant2/users/1").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK)
+}
+
+const abacModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == r.obj.Owner || r.act == "GET"
+`
+
+type document struct {
+	Owner string
+}
+
+func TestRequestBuilderABAC(t *testing.T) {
+	c := New(newTestEnforcer(t, abacModel))
+	c.RequestBuilder = Args(SubjectArg(), ValueArg("document"), MethodArg())
+
+	app := iris.New()
+	app.Any("/documents/{owner}", subjectFromHeader, func(ctx iris.Context) {
+		if owner := ctx.Params().Get("owner"); owner != "none" {
+			ctx.Values().Set("document", document{Owner: owner})
+		}
+		ctx.Next()
+	}, c.ServeHTTP, func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+
+	e := httptest.New(t, app)
+	e.PUT("/documents/alice").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+	e.PUT("/documents/alice").WithHeader("X-User", "bob").Expect().Status(httptest.StatusForbidden)
+	e.GET("/documents/alice").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK)
+	// missing object.
+	e.GET("/documents/none").WithHeader("X-User", "bob").Expect().Status(httptest.StatusInternalServerError)
+}
+
+func TestDefaultRequestBuilder(t *testing.T) {
+	c := New(newTestEnforcer(t, `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && keyMatch(r.obj, p.obj) && r.act == p.act
+`, []string{"alice", "/data/*", "GET"}))
+	c.RequestBuilder = nil
+
+	app := iris.New()
+	app.Get("/data/{id}", subjectFromHeader, c.ServeHTTP, func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+
+	e := httptest.New(t, app)
+	e.GET("/data/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+	e.GET("/data/1").WithHeader("X-User", "bob").Expect().Status(httptest.StatusForbidden)
+}
+
+func TestErrorHandler(t *testing.T) {
+	c := New(newTestEnforcer(t, domainsModel))
+	// the request definition requires 4 arguments.
+	c.RequestBuilder = Args(SubjectArg(), PathArg(), MethodArg())
+
+	var observed []Decision
+	c.Observers = []Observer{ObserverFunc(func(ctx iris.Context, d Decision) {
+		observed = append(observed, d)
+	})}
+
+	app := iris.New()
+	app.Get("/", subjectFromHeader, c.ServeHTTP, func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+
+	e := httptest.New(t, app)
+	e.GET("/").WithHeader("X-User", "alice").Expect().Status(httptest.StatusInternalServerError).Body().NotContains("request size")
+
+	if len(observed) != 1 || observed[0].Err == nil || observed[0].Allowed {
+		t.Fatalf("expected an error decision but got: %v", observed)
+	}
+
+	c.ErrorHandler = func(ctx iris.Context, err error) {
+		ctx.StopWithText(iris.StatusServiceUnavailable, "unavailable")
+	}
+	e.GET("/").WithHeader("X-User", "alice").Expect().Status(httptest.StatusServiceUnavailable).Body().IsEqual("unavailable")
+
+	c.RequestBuilder = func(ctx iris.Context, subject string) ([]interface{}, error) {
+		return nil, errors.New("no tenant")
+	}
+	e.GET("/").WithHeader("X-User", "alice").Expect().Status(httptest.StatusServiceUnavailable)
+	if err := observed[len(observed)-1].Err; err == nil || err.Error() != "no tenant" {
+		t.Fatalf("expected the request builder's error but got: %v", err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	c := New(newTestEnforcer(t, `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && keyMatch(r.obj, p.obj) && r.act == p.act
+`, []string{"alice", "/data/*", "GET"}))
+	c.Explain = true
+
+	app := iris.New()
+	app.Get("/data/{id}", subjectFromHeader, c.ServeHTTP, func(ctx iris.Context) {
+		d, ok := GetDecision(ctx)
+		if !ok {
+			t.Fatal("expected a decision")
+		}
+
+		ctx.Writef("%s", d)
+	})
+
+	e := httptest.New(t, app)
+	r := e.GET("/data/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+	r.Header(ExplainHeader).IsEqual("alice, /data/*, GET")
+	r.Body().IsEqual(`subject: "alice", object: /data/1, action: GET, allowed by [alice, /data/*, GET]`)
+
+	e.GET("/data/1").WithHeader("X-User", "bob").Expect().Status(httptest.StatusForbidden).Header(ExplainHeader).IsEmpty()
+}
@@ -0,0 +1,91 @@
+package casbin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+)
+
+const decisionContextKey = "iris.contrib.casbin.decision"
+
+// Decision holds the result of an authorization check, see GetDecision and Observer.
+type Decision struct {
+	// Subject is the subject of the request, see the Casbin.SubjectExtractor field.
+	Subject string
+	// Request holds the enforcer's arguments, see the Casbin.RequestBuilder field.
+	Request []interface{}
+	// Object and Action are the last two arguments of the Request,
+	// e.g. the path and the method of the default request definition.
+	Object, Action interface{}
+	// Allowed reports whether the request was authorized.
+	Allowed bool
+	// Rule is the policy rule which decided the request, e.g. ["alice", "/dataset1/*", "GET"].
+	// It is filled when the Casbin.Explain field is true or when an Observer is registered.
+	Rule []string
+	// Err is the error of the enforcer or of the request builder, if any.
+	// Requests are not allowed on errors, see the Casbin.ErrorHandler field.
+	Err error
+}
+
+func newDecision(subject string, request []interface{}) Decision {
+	d := Decision{Subject: subject, Request: request}
+	if n := len(request); n >= 2 {
+		d.Object, d.Action = request[n-2], request[n-1]
+	}
+
+	return d
+}
+
+// String returns a text representation of the decision, used by LogDecisions.
+func (d Decision) String() string {
+	result := "denied"
+	if d.Err != nil {
+		result = "error: " + d.Err.Error()
+	} else if d.Allowed {
+		result = "allowed"
+	}
+
+	s := fmt.Sprintf("subject: %q, object: %v, action: %v, %s", d.Subject, d.Object, d.Action, result)
+	if len(d.Rule) > 0 {
+		s += " by [" + strings.Join(d.Rule, ", ") + "]"
+	}
+
+	return s
+}
+
+// GetDecision returns the decision of the current request
+// made by a previous Casbin middleware, Check or Decide call.
+func GetDecision(ctx iris.Context) (Decision, bool) {
+	d, ok := ctx.Values().Get(decisionContextKey).(Decision)
+	return d, ok
+}
+
+// Observer is notified about each decision, e.g. to log or audit them.
+type Observer interface {
+	ObserveDecision(ctx iris.Context, d Decision)
+}
+
+// ObserverFunc is an adapter to use a function as an Observer.
+type ObserverFunc func(ctx iris.Context, d Decision)
+
+// ObserveDecision calls fn(ctx, d).
+func (fn ObserverFunc) ObserveDecision(ctx iris.Context, d Decision) {
+	fn(ctx, d)
+}
+
+// LogDecisions returns an Observer which logs the decisions through the Application's logger,
+// allowed ones at the debug level, denied ones at the info level and errors at the error level.
+func LogDecisions() Observer {
+	return ObserverFunc(func(ctx iris.Context, d Decision) {
+		logger := ctx.Application().Logger()
+		switch {
+		case d.Err != nil:
+			logger.Errorf("casbin: %s %s: %s", ctx.Method(), ctx.Path(), d)
+		case d.Allowed:
+			logger.Debugf("casbin: %s %s: %s", ctx.Method(), ctx.Path(), d)
+		default:
+			logger.Infof("casbin: %s %s: %s", ctx.Method(), ctx.Path(), d)
+		}
+	})
+}
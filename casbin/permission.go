@@ -0,0 +1,229 @@
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/core/router"
+)
+
+const (
+	permissionContextKey = "iris.contrib.casbin.permission"
+	resourcePropertyKey  = "iris.contrib.casbin.resource"
+)
+
+// ErrNoPermission is returned by the ResourceArg and ActionArg arguments
+// when the current route has no declared permission. Such requests are denied.
+var ErrNoPermission = errors.New("casbin: no permission declared for the route")
+
+// Permission is the resource and the action required by a route,
+// see the Casbin.Require and RequireParty methods.
+type Permission struct {
+	Resource string
+	Action   string
+}
+
+// MethodActions maps the HTTP methods to the actions of the permissions
+// declared without an action, e.g. through the RequireParty method.
+// Methods which are missing from the map are lowercased instead, e.g. "PROPFIND" to "propfind".
+var MethodActions = map[string]string{
+	iris.MethodGet:     "read",
+	iris.MethodHead:    "read",
+	iris.MethodOptions: "read",
+	iris.MethodPost:    "create",
+	iris.MethodPut:     "update",
+	iris.MethodPatch:   "update",
+	iris.MethodDelete:  "delete",
+}
+
+func methodAction(method string) string {
+	if action, ok := MethodActions[method]; ok {
+		return action
+	}
+
+	return strings.ToLower(method)
+}
+
+// routePermissions holds the route-level permissions of a Casbin middleware.
+type routePermissions struct {
+	mu     sync.RWMutex
+	routes map[*router.Route]Permission
+	// byName indexes the routes by their names when they are served, as names may change until then.
+	byName map[string]Permission
+}
+
+func (p *routePermissions) set(route *router.Route, perm Permission) {
+	p.mu.Lock()
+	if p.routes == nil {
+		p.routes = make(map[*router.Route]Permission)
+	}
+	p.routes[route] = perm
+	p.byName = nil
+	p.mu.Unlock()
+}
+
+func (p *routePermissions) get(name string) (Permission, bool) {
+	p.mu.RLock()
+	if len(p.routes) == 0 {
+		p.mu.RUnlock()
+		return Permission{}, false
+	}
+
+	byName := p.byName
+	p.mu.RUnlock()
+
+	if byName == nil {
+		p.mu.Lock()
+		if p.byName == nil {
+			p.byName = make(map[string]Permission, len(p.routes))
+			for route, perm := range p.routes {
+				p.byName[route.Name] = perm
+			}
+		}
+		byName = p.byName
+		p.mu.Unlock()
+	}
+
+	perm, ok := byName[name]
+	return perm, ok
+}
+
+func (p *routePermissions) has(route *router.Route) bool {
+	p.mu.RLock()
+	_, ok := p.routes[route]
+	p.mu.RUnlock()
+	return ok
+}
+
+// Require declares the permission required by the "route", so policies can refer
+// to a resource and an action instead of the route's paths, e.g.
+//
+//	p, admin, users, delete
+//
+// If the "action" is empty then it is derived from the route's method, see MethodActions.
+// It returns the route itself. The permission is enforced by the PermissionRequestBuilder.
+//
+// Usage:
+//
+//	casbinMiddleware.Require(app.Delete("/users/{id}", deleteUser), "users", "delete")
+func (c *Casbin) Require(route *router.Route, resource, action string) *router.Route {
+	if action == "" {
+		action = methodAction(route.Method)
+	}
+
+	c.permissions.set(route, Permission{Resource: resource, Action: action})
+	return route
+}
+
+// RequireParty declares the "resource" required by the routes of the party "p" and its children parties
+// created afterwards, the action is derived from the route's method, see MethodActions.
+// Route-level permissions, see Require, have priority.
+//
+// Usage:
+//
+//	users := app.Party("/users")
+//	casbinMiddleware.RequireParty(users, "users")
+func (c *Casbin) RequireParty(p iris.Party, resource string) {
+	p.Properties()[resourcePropertyKey] = resource
+}
+
+// permission returns the permission declared for the current route.
+func (c *Casbin) permission(ctx iris.Context) (Permission, bool) {
+	route := ctx.GetCurrentRoute()
+	if route == nil {
+		return Permission{}, false
+	}
+
+	if perm, ok := c.permissions.get(route.Name()); ok {
+		return perm, true
+	}
+
+	if v, ok := route.Property(resourcePropertyKey); ok {
+		if resource, ok := v.(string); ok {
+			return Permission{Resource: resource, Action: methodAction(route.Method())}, true
+		}
+	}
+
+	return Permission{}, false
+}
+
+// GetPermission returns the permission declared for the current route,
+// available after the Casbin middleware.
+func GetPermission(ctx iris.Context) (Permission, bool) {
+	perm, ok := ctx.Values().Get(permissionContextKey).(Permission)
+	return perm, ok
+}
+
+// ResourceArg returns the resource of the permission declared for the current route.
+// It fails with ErrNoPermission when the route has no declared permission.
+func ResourceArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		perm, ok := GetPermission(ctx)
+		if !ok {
+			return nil, ErrNoPermission
+		}
+
+		return perm.Resource, nil
+	}
+}
+
+// ActionArg returns the action of the permission declared for the current route.
+// It fails with ErrNoPermission when the route has no declared permission.
+func ActionArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		perm, ok := GetPermission(ctx)
+		if !ok {
+			return nil, ErrNoPermission
+		}
+
+		return perm.Action, nil
+	}
+}
+
+// PermissionRequestBuilder builds the `{subject, resource, action}` arguments
+// of the permission declared for the current route, see the Require and RequireParty methods.
+// Requests to routes without a declared permission are denied.
+// The middleware should be registered through Use or Done, not UseRouter, as it requires a matched route.
+//
+// Usage:
+//
+//	casbinMiddleware.RequestBuilder = casbin.PermissionRequestBuilder
+var PermissionRequestBuilder = Args(SubjectArg(), ResourceArg(), ActionArg())
+
+// CheckRoutes reports the "routes" without a declared permission, e.g. at startup:
+//
+//	if err := casbinMiddleware.CheckRoutes(app.GetRoutes(), "GET/login"); err != nil {
+//		app.Logger().Fatal(err)
+//	}
+//
+// Routes named by the "skip" names and HTTP error handlers are not checked.
+// The routes which do not run the Casbin middleware should be skipped too.
+func (c *Casbin) CheckRoutes(routes []*router.Route, skip ...string) error {
+	var errs []error
+	for _, route := range routes {
+		if route.StatusCode > 0 || contains(skip, route.Name) || c.permissions.has(route) {
+			continue
+		}
+
+		if _, ok := route.Party.Properties()[resourcePropertyKey]; ok {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("%s: no permission declared", route.Name))
+	}
+
+	return errors.Join(errs...)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package casbin
+
+import (
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+)
+
+func TestPermissions(t *testing.T) {
+	e := newTestEnforcer(t, rbacModel,
+		[]string{"admin", "users", "(read)|(create)|(delete)"},
+		[]string{"editor", "users", "read"},
+		[]string{"editor", "reports", "export"},
+	)
+	if _, err := e.AddGroupingPolicy("alice", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddGroupingPolicy("bob", "editor"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(e)
+	c.RequestBuilder = PermissionRequestBuilder
+
+	app := iris.New()
+	app.Use(subjectFromHeader, c.ServeHTTP)
+
+	handler := func(ctx iris.Context) {
+		perm, _ := GetPermission(ctx)
+		ctx.Writef("%s:%s", perm.Resource, perm.Action)
+	}
+
+	users := app.Party("/users")
+	c.RequireParty(users, "users")
+	users.Get("/", handler)
+	users.Post("/", handler)
+	users.Delete("/{id}", handler)
+	c.Require(users.Post("/{id}/export", handler), "reports", "export")
+
+	app.Get("/health", handler)
+
+	te := httptest.New(t, app)
+	te.GET("/users").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK).Body().IsEqual("users:read")
+	te.POST("/users").WithHeader("X-User", "bob").Expect().Status(httptest.StatusForbidden)
+	te.POST("/users").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK).Body().IsEqual("users:create")
+	te.DELETE("/users/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK).Body().IsEqual("users:delete")
+	// route-level permissions have priority over the party's ones.
+	te.POST("/users/1/export").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK).Body().IsEqual("reports:export")
+	te.POST("/users/1/export").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+	// routes without a declared permission are denied.
+	te.GET("/health").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+}
+
+func TestCheckRoutes(t *testing.T) {
+	c := New(newTestEnforcer(t, rbacModel))
+
+	app := iris.New()
+	app.OnErrorCode(iris.StatusNotFound, func(ctx iris.Context) {})
+	app.Get("/login", func(ctx iris.Context) {})
+	app.Get("/health", func(ctx iris.Context) {})
+	c.Require(app.Get("/reports", func(ctx iris.Context) {}), "reports", "")
+
+	users := app.Party("/users")
+	c.RequireParty(users, "users")
+	users.Get("/", func(ctx iris.Context) {})
+	users.Party("/{id}").Get("/posts", func(ctx iris.Context) {})
+
+	err := c.CheckRoutes(app.GetRoutes(), "GET/login")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if expected := "GET/health: no permission declared"; err.Error() != expected {
+		t.Fatalf("expected error %q but got %q", expected, err.Error())
+	}
+
+	if err = c.CheckRoutes(app.GetRoutes(), "GET/login", "GET/health"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,475 @@
+// Package pgadapter provides a casbin policy adapter backed by a PostgreSQL database
+// through the github.com/kataras/pg package, the one used by the iris-contrib/middleware/pg middleware.
+package pgadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/kataras/pg"
+	"github.com/kataras/pg/desc"
+)
+
+// DefaultTableName is the default policy table name.
+const DefaultTableName = "casbin_rule"
+
+// maxValues is the number of the policy table's value columns, v0 to v5.
+const maxValues = 6
+
+// Rule is the policy table's model. Register it to the application's schema
+// to let pg.DB.CreateSchema and pg.DB.CheckSchema manage the policy table, e.g.
+//
+//	schema.MustRegister(pgadapter.DefaultTableName, pgadapter.Rule{})
+//
+// Otherwise set the Options.AutoMigrate field to true.
+type Rule struct {
+	ID    int64  `pg:"type=bigserial,primary"`
+	PType string `pg:"name=ptype,type=varchar(100),unique_index=uq_casbin_rule"`
+	V0    string `pg:"type=varchar(255),default='',unique_index=uq_casbin_rule"`
+	V1    string `pg:"type=varchar(255),default='',unique_index=uq_casbin_rule"`
+	V2    string `pg:"type=varchar(255),default='',unique_index=uq_casbin_rule"`
+	V3    string `pg:"type=varchar(255),default='',unique_index=uq_casbin_rule"`
+	V4    string `pg:"type=varchar(255),default='',unique_index=uq_casbin_rule"`
+	V5    string `pg:"type=varchar(255),default='',unique_index=uq_casbin_rule"`
+}
+
+// Options holds the Adapter's options.
+type Options struct {
+	// TableName is the policy table name, it may be qualified by a schema, e.g. "auth.casbin_rule".
+	// Defaults to DefaultTableName.
+	TableName string
+	// AutoMigrate, if true, creates the policy table if it does not exist.
+	AutoMigrate bool
+}
+
+// Filter is the filter of the Adapter's LoadFilteredPolicy method.
+// Each field matches any of its values, empty fields match all the rules.
+// Empty values match any value too, e.g. V0: []string{""}.
+type Filter struct {
+	PType []string
+	V0    []string
+	V1    []string
+	V2    []string
+	V3    []string
+	V4    []string
+	V5    []string
+}
+
+// Adapter is a casbin policy adapter backed by a PostgreSQL database.
+// It implements the persist.Adapter, persist.FilteredAdapter,
+// persist.BatchAdapter and persist.UpdatableAdapter interfaces.
+// Batch and update operations run in a single transaction.
+type Adapter struct {
+	db        *pg.DB
+	tableName string
+	filtered  bool
+}
+
+var (
+	_ persist.Adapter          = (*Adapter)(nil)
+	_ persist.FilteredAdapter  = (*Adapter)(nil)
+	_ persist.BatchAdapter     = (*Adapter)(nil)
+	_ persist.UpdatableAdapter = (*Adapter)(nil)
+)
+
+var tableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// New returns a new Adapter of the "db" database.
+// The *pg.DB can be retrieved from the pg middleware through its GetDB method.
+//
+// Example:
+//
+//	a, err := pgadapter.New(db, pgadapter.Options{AutoMigrate: true})
+//	e, err := casbin.NewEnforcer("casbinmodel.conf", a)
+func New(db *pg.DB, opts Options) (*Adapter, error) {
+	tableName := opts.TableName
+	if tableName == "" {
+		tableName = DefaultTableName
+	}
+
+	if !tableNameRegexp.MatchString(tableName) {
+		return nil, fmt.Errorf("pgadapter: invalid table name: %q", tableName)
+	}
+
+	a := &Adapter{
+		db:        db,
+		tableName: tableName,
+	}
+
+	if opts.AutoMigrate {
+		if err := a.Migrate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Migrate creates the policy table if it does not exist.
+func (a *Adapter) Migrate(ctx context.Context) error {
+	query, err := createTableQuery(a.tableName)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.db.Exec(ctx, query)
+	return err
+}
+
+// createTableQuery returns the "CREATE TABLE IF NOT EXISTS" query of the Rule model.
+func createTableQuery(tableName string) (string, error) {
+	td, err := pg.NewSchema().Register(tableName, Rule{})
+	if err != nil {
+		return "", err
+	}
+
+	// unique index names are unique per schema.
+	uniqueIndex := "uq_" + strings.ReplaceAll(tableName, ".", "_")
+	for _, col := range td.Columns {
+		if col.UniqueIndex != "" {
+			col.UniqueIndex = uniqueIndex
+		}
+	}
+
+	return desc.BuildCreateTableQuery(td), nil
+}
+
+// LoadPolicy loads all the policy rules from the database.
+func (a *Adapter) LoadPolicy(m model.Model) error {
+	a.filtered = false
+	return a.loadPolicy(m, "", nil)
+}
+
+// LoadFilteredPolicy loads the policy rules which match the "filter", a Filter or a *Filter value.
+func (a *Adapter) LoadFilteredPolicy(m model.Model, filter interface{}) error {
+	var f Filter
+	switch v := filter.(type) {
+	case nil:
+		return a.LoadPolicy(m)
+	case Filter:
+		f = v
+	case *Filter:
+		if v == nil {
+			return a.LoadPolicy(m)
+		}
+		f = *v
+	default:
+		return fmt.Errorf("pgadapter: invalid filter type: %T", filter)
+	}
+
+	where, args := f.where()
+	if err := a.loadPolicy(m, where, args); err != nil {
+		return err
+	}
+
+	a.filtered = true
+	return nil
+}
+
+// IsFiltered reports whether the loaded policy has been filtered.
+func (a *Adapter) IsFiltered() bool {
+	return a.filtered
+}
+
+func (a *Adapter) loadPolicy(m model.Model, where string, args []any) error {
+	rules, err := a.selectRules(context.Background(), a.db, where, args)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if err = persist.LoadPolicyArray(rule, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectRules returns the rules which match the "where" condition, each rule starts with its ptype.
+func (a *Adapter) selectRules(ctx context.Context, db *pg.DB, where string, args []any) ([][]string, error) {
+	query := `SELECT ptype, v0, v1, v2, v3, v4, v5 FROM ` + a.tableName
+	if where != "" {
+		query += ` WHERE ` + where
+	}
+	query += ` ORDER BY id;`
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules [][]string
+	for rows.Next() {
+		rule := make([]string, maxValues+1)
+		if err = rows.Scan(&rule[0], &rule[1], &rule[2], &rule[3], &rule[4], &rule[5], &rule[6]); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, trimRule(rule))
+	}
+
+	if err = rows.Err(); err != nil && !errors.Is(err, pg.ErrNoRows) {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// trimRule removes the trailing empty values of a rule.
+func trimRule(rule []string) []string {
+	for len(rule) > 0 && rule[len(rule)-1] == "" {
+		rule = rule[:len(rule)-1]
+	}
+
+	return rule
+}
+
+// SavePolicy replaces the database's policy rules with the model's ones.
+func (a *Adapter) SavePolicy(m model.Model) error {
+	if a.filtered {
+		return errors.New("pgadapter: cannot save a filtered policy")
+	}
+
+	ctx := context.Background()
+	return a.db.InTransaction(ctx, func(db *pg.DB) error {
+		if _, err := db.Exec(ctx, `DELETE FROM `+a.tableName+`;`); err != nil {
+			return err
+		}
+
+		for _, sec := range []string{"p", "g"} {
+			for ptype, ast := range m[sec] {
+				for _, rule := range ast.Policy {
+					if err := a.insertRule(ctx, db, ptype, rule); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// AddPolicy adds a policy rule to the database.
+func (a *Adapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.insertRule(context.Background(), a.db, ptype, rule)
+}
+
+// AddPolicies adds policy rules to the database.
+func (a *Adapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	ctx := context.Background()
+	return a.db.InTransaction(ctx, func(db *pg.DB) error {
+		for _, rule := range rules {
+			if err := a.insertRule(ctx, db, ptype, rule); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (a *Adapter) insertRule(ctx context.Context, db *pg.DB, ptype string, rule []string) error {
+	args, err := ruleArgs(ptype, rule)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO ` + a.tableName + ` (ptype, v0, v1, v2, v3, v4, v5) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT DO NOTHING;`
+	_, err = db.Exec(ctx, query, args...)
+	return err
+}
+
+// ruleArgs returns the ptype followed by the rule's values padded to the value columns.
+func ruleArgs(ptype string, rule []string) ([]any, error) {
+	if len(rule) > maxValues {
+		return nil, fmt.Errorf("pgadapter: rule exceeds %d values: %v", maxValues, rule)
+	}
+
+	args := make([]any, maxValues+1)
+	args[0] = ptype
+	for i := 0; i < maxValues; i++ {
+		if i < len(rule) {
+			args[i+1] = rule[i]
+		} else {
+			args[i+1] = ""
+		}
+	}
+
+	return args, nil
+}
+
+const ruleCondition = `ptype = $1 AND v0 = $2 AND v1 = $3 AND v2 = $4 AND v3 = $5 AND v4 = $6 AND v5 = $7`
+
+// RemovePolicy removes a policy rule from the database.
+func (a *Adapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.deleteRule(context.Background(), a.db, ptype, rule)
+}
+
+// RemovePolicies removes policy rules from the database.
+func (a *Adapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	ctx := context.Background()
+	return a.db.InTransaction(ctx, func(db *pg.DB) error {
+		for _, rule := range rules {
+			if err := a.deleteRule(ctx, db, ptype, rule); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (a *Adapter) deleteRule(ctx context.Context, db *pg.DB, ptype string, rule []string) error {
+	args, err := ruleArgs(ptype, rule)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `DELETE FROM `+a.tableName+` WHERE `+ruleCondition+`;`, args...)
+	return err
+}
+
+// RemoveFilteredPolicy removes the policy rules which match the "fieldValues"
+// starting from the "fieldIndex", empty values match any value.
+func (a *Adapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	where, args, err := fieldsCondition(ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.db.Exec(context.Background(), `DELETE FROM `+a.tableName+` WHERE `+where+`;`, args...)
+	return err
+}
+
+// fieldsCondition returns the condition of the rules which match the "fieldValues" starting from the "fieldIndex".
+func fieldsCondition(ptype string, fieldIndex int, fieldValues ...string) (string, []any, error) {
+	if fieldIndex < 0 || fieldIndex+len(fieldValues) > maxValues {
+		return "", nil, fmt.Errorf("pgadapter: invalid field index: %d", fieldIndex)
+	}
+
+	conditions := []string{"ptype = $1"}
+	args := []any{ptype}
+	for i, v := range fieldValues {
+		if v == "" {
+			continue
+		}
+
+		args = append(args, v)
+		conditions = append(conditions, "v"+strconv.Itoa(fieldIndex+i)+" = $"+strconv.Itoa(len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// UpdatePolicy updates a policy rule of the database.
+func (a *Adapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return a.UpdatePolicies(sec, ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+// UpdatePolicies updates policy rules of the database, the "oldRules" are replaced by the "newRules" in order.
+func (a *Adapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	if len(oldRules) != len(newRules) {
+		return fmt.Errorf("pgadapter: expected %d new rules but got %d", len(oldRules), len(newRules))
+	}
+
+	ctx := context.Background()
+	return a.db.InTransaction(ctx, func(db *pg.DB) error {
+		for i := range oldRules {
+			oldArgs, err := ruleArgs(ptype, oldRules[i])
+			if err != nil {
+				return err
+			}
+
+			newArgs, err := ruleArgs(ptype, newRules[i])
+			if err != nil {
+				return err
+			}
+
+			query := `UPDATE ` + a.tableName + ` SET v0 = $8, v1 = $9, v2 = $10, v3 = $11, v4 = $12, v5 = $13 WHERE ` + ruleCondition + `;`
+			if _, err = db.Exec(ctx, query, append(oldArgs, newArgs[1:]...)...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UpdateFilteredPolicies replaces the policy rules which match the "fieldValues" starting from the "fieldIndex"
+// with the "newRules" and returns the replaced rules.
+func (a *Adapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	where, args, err := fieldsCondition(ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldRules [][]string
+	ctx := context.Background()
+	err = a.db.InTransaction(ctx, func(db *pg.DB) error {
+		rules, err := a.selectRules(ctx, db, where, args)
+		if err != nil {
+			return err
+		}
+
+		if _, err = db.Exec(ctx, `DELETE FROM `+a.tableName+` WHERE `+where+`;`, args...); err != nil {
+			return err
+		}
+
+		for _, rule := range newRules {
+			if err = a.insertRule(ctx, db, ptype, rule); err != nil {
+				return err
+			}
+		}
+
+		oldRules = make([][]string, 0, len(rules))
+		for _, rule := range rules {
+			oldRules = append(oldRules, rule[1:]) // without the ptype.
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return oldRules, nil
+}
+
+// where returns the condition of the rules which match the filter.
+func (f Filter) where() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	for i, values := range [...][]string{f.PType, f.V0, f.V1, f.V2, f.V3, f.V4, f.V5} {
+		column := "ptype"
+		if i > 0 {
+			column = "v" + strconv.Itoa(i-1)
+		}
+
+		if len(values) == 0 || slices.Contains(values, "") { // matches any value.
+			continue
+		}
+
+		placeholders := make([]string, 0, len(values))
+		for _, v := range values {
+			args = append(args, v)
+			placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+		}
+
+		conditions = append(conditions, column+" IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,194 @@
+package pgadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/iris-contrib/middleware/casbin"
+	"github.com/kataras/pg"
+)
+
+// DefaultChannel is the default notification channel of the Watcher.
+const DefaultChannel = "casbin_policy"
+
+// maxPayloadSize is the maximum notification payload size,
+// PostgreSQL limits it to 8000 bytes.
+const maxPayloadSize = 7900
+
+// reconnectDelay is the delay between the reconnection attempts of the Watcher's listener.
+var reconnectDelay = time.Second
+
+var channelRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Watcher is a casbin.Watcher which delivers the policy changes
+// through the PostgreSQL LISTEN and NOTIFY commands.
+// Changes larger than a notification payload are delivered as a reload.
+// Missed changes, e.g. on a lost connection, are recovered by a reload.
+type Watcher struct {
+	db      *pg.DB
+	channel string
+
+	mu          sync.RWMutex
+	subscribers []func(casbin.PolicyChange)
+	onError     func(error)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ casbin.Watcher = (*Watcher)(nil)
+
+// NewWatcher returns a new Watcher which listens to the "channel" of the "db" database.
+// The "onError" optional function is called when a notification cannot be received or decoded,
+// e.g. on a lost connection.
+//
+// Example:
+//
+//	w, err := pgadapter.NewWatcher(db, pgadapter.DefaultChannel, nil)
+//	err = casbinMiddleware.Watch(w, nil)
+func NewWatcher(db *pg.DB, channel string, onError func(error)) (*Watcher, error) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+
+	if !channelRegexp.MatchString(channel) {
+		return nil, fmt.Errorf("pgadapter: invalid channel name: %q", channel)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l, err := db.Listen(ctx, channel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	w := &Watcher{
+		db:      db,
+		channel: channel,
+		onError: onError,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go w.listen(ctx, l)
+	return w, nil
+}
+
+// Publish sends the change through a notification.
+func (w *Watcher) Publish(ctx context.Context, change casbin.PolicyChange) error {
+	payload, err := encodeChange(change)
+	if err != nil {
+		return err
+	}
+
+	return w.db.Notify(ctx, w.channel, payload)
+}
+
+// encodeChange returns the notification payload of a change.
+func encodeChange(change casbin.PolicyChange) ([]byte, error) {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) > maxPayloadSize {
+		return json.Marshal(casbin.PolicyChange{Op: casbin.PolicyReload, Origin: change.Origin})
+	}
+
+	return payload, nil
+}
+
+// Subscribe registers a function which is called on each received change.
+func (w *Watcher) Subscribe(fn func(casbin.PolicyChange)) error {
+	w.mu.Lock()
+	w.subscribers = append(w.subscribers, fn)
+	w.mu.Unlock()
+	return nil
+}
+
+// Close stops listening for notifications.
+func (w *Watcher) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) listen(ctx context.Context, l *pg.Listener) {
+	defer close(w.done)
+
+	for {
+		n, err := l.Accept(ctx)
+		if err == nil {
+			var change casbin.PolicyChange
+			if change, err = pg.UnmarshalNotification[casbin.PolicyChange](n); err == nil {
+				w.notify(change)
+				continue
+			}
+		}
+
+		if ctx.Err() != nil {
+			_ = l.Close(context.Background())
+			return
+		}
+
+		w.handleError(err)
+		if errors.Is(err, pg.ErrEmptyPayload) || isDecodeError(err) {
+			continue
+		}
+
+		// the connection is lost, listen again and reload as changes may be missed.
+		_ = l.Close(context.Background())
+		if l = w.relisten(ctx); l == nil {
+			return
+		}
+
+		w.notify(casbin.PolicyChange{Op: casbin.PolicyReload})
+	}
+}
+
+func isDecodeError(err error) bool {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// relisten returns a new listener or nil if the watcher is closed.
+func (w *Watcher) relisten(ctx context.Context) *pg.Listener {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+
+		l, err := w.db.Listen(ctx, w.channel)
+		if err == nil {
+			return l
+		}
+
+		w.handleError(err)
+	}
+}
+
+func (w *Watcher) notify(change casbin.PolicyChange) {
+	w.mu.RLock()
+	subscribers := w.subscribers
+	w.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
+
+func (w *Watcher) handleError(err error) {
+	if w.onError != nil {
+		w.onError(fmt.Errorf("pgadapter: watcher: %w", err))
+	}
+}
@@ -0,0 +1,84 @@
+package pgadapter
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iris-contrib/middleware/casbin"
+	"github.com/kataras/pg"
+)
+
+func TestEncodeChange(t *testing.T) {
+	change := casbin.PolicyChange{Op: casbin.PolicyAdd, Sec: "p", PType: "p", Rules: [][]string{{"alice", "/data", "GET"}}, Origin: "a"}
+	payload, err := encodeChange(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"op":"add","sec":"p","ptype":"p","rules":[["alice","/data","GET"]],"origin":"a"}`; string(payload) != expected {
+		t.Fatalf("expected %s but got %s", expected, payload)
+	}
+
+	// too large for a notification.
+	change.Rules = [][]string{{strings.Repeat("a", maxPayloadSize), "/data", "GET"}}
+	if payload, err = encodeChange(change); err != nil {
+		t.Fatal(err)
+	}
+
+	var got casbin.PolicyChange
+	if err = json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Op != casbin.PolicyReload || got.Origin != "a" || len(got.Rules) != 0 {
+		t.Fatalf("expected a reload change but got: %#+v", got)
+	}
+
+	if _, err = NewWatcher(nil, "casbin; DROP TABLE users", nil); err == nil {
+		t.Fatal("expected an invalid channel name error")
+	}
+}
+
+// TestWatcher runs against the database of the PG_CONNECTION environment variable, see TestAdapter.
+func TestWatcher(t *testing.T) {
+	connString := os.Getenv("PG_CONNECTION")
+	if connString == "" {
+		t.Skip("PG_CONNECTION is not set")
+	}
+
+	ctx := context.Background()
+	db, err := pg.Open(ctx, pg.NewSchema(), connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	w, err := NewWatcher(db, "casbin_policy_test", func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	changes := make(chan casbin.PolicyChange, 1)
+	if err = w.Subscribe(func(change casbin.PolicyChange) { changes <- change }); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := casbin.PolicyChange{Op: casbin.PolicyRemove, Sec: "g", PType: "g", Rules: [][]string{{"alice", "admin"}}, Origin: "a"}
+	if err = w.Publish(ctx, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-changes:
+		if got.Op != expected.Op || got.Origin != expected.Origin || got.Rules[0][1] != "admin" {
+			t.Fatalf("expected %#+v but got %#+v", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification not received")
+	}
+}
@@ -0,0 +1,165 @@
+package casbin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+)
+
+// RequestBuilder builds the arguments of the casbin enforcer for the current request,
+// they should match the model's request definition, e.g. `r = sub, dom, obj, act`.
+// See the Casbin.RequestBuilder field and the Args function.
+type RequestBuilder func(ctx iris.Context, subject string) ([]interface{}, error)
+
+// DefaultRequestBuilder builds the `{subject, path, method}` arguments.
+func DefaultRequestBuilder(ctx iris.Context, subject string) ([]interface{}, error) {
+	return []interface{}{subject, ctx.Path(), ctx.Method()}, nil
+}
+
+// Arg returns an argument of the casbin enforcer for the current request, see Args.
+type Arg func(ctx iris.Context, subject string) (interface{}, error)
+
+// Args returns a RequestBuilder of the "args" in order.
+//
+// RBAC with domains, `r = sub, dom, obj, act`:
+//
+//	c.RequestBuilder = casbin.Args(casbin.SubjectArg(), casbin.SubdomainArg(), casbin.RouteArg(), casbin.MethodArg())
+//
+// ABAC, `r = sub, obj, act` with `m = r.sub == r.obj.Owner`:
+//
+//	c.RequestBuilder = casbin.Args(casbin.SubjectArg(), casbin.ValueArg("document"), casbin.MethodArg())
+func Args(args ...Arg) RequestBuilder {
+	return func(ctx iris.Context, subject string) ([]interface{}, error) {
+		values := make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			v, err := arg(ctx, subject)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, v)
+		}
+
+		return values, nil
+	}
+}
+
+// SubjectArg returns the subject, see the Casbin.SubjectExtractor field.
+func SubjectArg() Arg {
+	return func(_ iris.Context, subject string) (interface{}, error) {
+		return subject, nil
+	}
+}
+
+// UserArg returns the authenticated user (see Context.User) for ABAC models, e.g. `r.sub.Roles`.
+// It fails when the request is not authenticated.
+func UserArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		u := ctx.User()
+		if u == nil {
+			return nil, fmt.Errorf("casbin: no user")
+		}
+
+		return u, nil
+	}
+}
+
+// PathArg returns the request path.
+func PathArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		return ctx.Path(), nil
+	}
+}
+
+// MethodArg returns the request method.
+func MethodArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		return ctx.Method(), nil
+	}
+}
+
+// RouteArg returns the path template of the current route, e.g. "/users/{id:uint64}",
+// so a policy can refer to all the paths of a route.
+// It fails when no route matched, e.g. when the middleware is registered through UseRouter.
+func RouteArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		route := ctx.GetCurrentRoute()
+		if route == nil {
+			return nil, fmt.Errorf("casbin: no route")
+		}
+
+		return route.Path(), nil
+	}
+}
+
+// RouteNameArg returns the name of the current route, e.g. "GET/users/{id:uint64}" or a custom name.
+// It fails when no route matched, e.g. when the middleware is registered through UseRouter.
+func RouteNameArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		route := ctx.GetCurrentRoute()
+		if route == nil {
+			return nil, fmt.Errorf("casbin: no route")
+		}
+
+		return route.Name(), nil
+	}
+}
+
+// HostArg returns the request host without its port, e.g. a tenant's domain.
+func HostArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		host := ctx.Host()
+		if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+			host = host[:i]
+		}
+
+		return host, nil
+	}
+}
+
+// SubdomainArg returns the request subdomain, e.g. a tenant.
+func SubdomainArg() Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		return ctx.Subdomain(), nil
+	}
+}
+
+// ParamArg returns a path parameter, e.g. a tenant ID. It fails when the parameter is missing.
+func ParamArg(name string) Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		v := ctx.Params().GetEntry(name)
+		if v.Key == "" {
+			return nil, fmt.Errorf("casbin: missing path parameter %q", name)
+		}
+
+		return v.String(), nil
+	}
+}
+
+// HeaderArg returns a request header.
+func HeaderArg(name string) Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		return ctx.GetHeader(name), nil
+	}
+}
+
+// ValueArg returns a context value (see Context.Values), e.g. an object loaded by a previous handler for ABAC models.
+// It fails when the value is missing.
+func ValueArg(key string) Arg {
+	return func(ctx iris.Context, _ string) (interface{}, error) {
+		v := ctx.Values().Get(key)
+		if v == nil {
+			return nil, fmt.Errorf("casbin: missing context value %q", key)
+		}
+
+		return v, nil
+	}
+}
+
+// ConstArg returns a constant value, e.g. a fixed domain.
+func ConstArg(value interface{}) Arg {
+	return func(iris.Context, string) (interface{}, error) {
+		return value, nil
+	}
+}
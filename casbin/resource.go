@@ -0,0 +1,113 @@
+package casbin
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+// Authorizer checks the permissions of the current request's subject on specific resources,
+// e.g. on entities loaded from a database, inside handlers and MVC controllers.
+// The subject is extracted by the Casbin.SubjectExtractor.
+// Unlike the Casbin middleware, the enforcer's arguments are given by the caller,
+// the subject is prepended to them.
+//
+// Usage:
+//
+//	auth := casbinMiddleware.Authorizer(ctx)
+//	ok, err := auth.Can(document.ID, "write")
+//
+// Register the Casbin.Authorizer method as a dependency to inject it in MVC controllers:
+//
+//	m := mvc.New(app.Party("/documents"))
+//	m.Register(casbinMiddleware.Authorizer)
+//	// type documentsController struct { Auth *casbin.Authorizer }
+type Authorizer struct {
+	casbin  *Casbin
+	ctx     iris.Context
+	subject string
+}
+
+// Authorizer returns a new Authorizer of the current request.
+func (c *Casbin) Authorizer(ctx iris.Context) *Authorizer {
+	return &Authorizer{
+		casbin:  c,
+		ctx:     ctx,
+		subject: c.SubjectExtractor(ctx),
+	}
+}
+
+// Subject returns the current request's subject.
+func (a *Authorizer) Subject() string {
+	return a.subject
+}
+
+// Context returns the current request's Context.
+func (a *Authorizer) Context() iris.Context {
+	return a.ctx
+}
+
+// Can reports whether the subject is allowed to access the resource of the "rvals" request values,
+// e.g. `{object, action}` for a `r = sub, obj, act` request definition.
+func (a *Authorizer) Can(rvals ...interface{}) (bool, error) {
+	args := make([]interface{}, 0, len(rvals)+1)
+	args = append(args, a.subject)
+	args = append(args, rvals...)
+
+	allowed, _, err := a.casbin.enforce(args)
+	return allowed, err
+}
+
+// AllowedActions returns the "actions" which the subject is allowed to do on the "object",
+// for a `r = sub, obj, act` request definition.
+// If no actions are given then all the actions of the policy are checked,
+// which requires a policy without action patterns, e.g. regular expressions.
+func (a *Authorizer) AllowedActions(object interface{}, actions ...string) ([]string, error) {
+	if len(actions) == 0 {
+		a.casbin.mu.RLock()
+		all, err := a.casbin.enforcer.GetAllActions()
+		a.casbin.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+
+		actions = all
+	}
+
+	allowed := make([]string, 0, len(actions))
+	for _, action := range actions {
+		ok, err := a.Can(object, action)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			allowed = append(allowed, action)
+		}
+	}
+
+	return allowed, nil
+}
+
+// Filter returns the "items" which the subject of "a" is allowed to access,
+// the "rvals" function returns the request values of an item, see the Authorizer.Can method.
+// The "items" slice is not modified.
+//
+// Usage:
+//
+//	documents, err = casbin.Filter(auth, documents, func(d Document) []interface{} {
+//		return []interface{}{d.ID, "read"}
+//	})
+func Filter[T any](a *Authorizer, items []T, rvals func(T) []interface{}) ([]T, error) {
+	allowed := make([]T, 0, len(items))
+	for _, item := range items {
+		ok, err := a.Can(rvals(item)...)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			allowed = append(allowed, item)
+		}
+	}
+
+	return allowed, nil
+}
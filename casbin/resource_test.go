@@ -0,0 +1,103 @@
+package casbin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+const documentsModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+
+type resourceDocument struct {
+	ID string
+}
+
+type documentsController struct {
+	Auth *Authorizer
+}
+
+func (c *documentsController) Get() ([]resourceDocument, error) {
+	documents := []resourceDocument{{"doc1"}, {"doc2"}, {"doc3"}}
+	return Filter(c.Auth, documents, func(d resourceDocument) []interface{} {
+		return []interface{}{d.ID, "read"}
+	})
+}
+
+func (c *documentsController) GetBy(id string) ([]string, error) {
+	return c.Auth.AllowedActions(id)
+}
+
+func TestAuthorizer(t *testing.T) {
+	c := New(newTestEnforcer(t, documentsModel,
+		[]string{"alice", "doc1", "read"},
+		[]string{"alice", "doc1", "write"},
+		[]string{"alice", "doc3", "read"},
+		[]string{"bob", "doc2", "read"},
+		[]string{"bob", "doc2", "delete"},
+	))
+
+	app := iris.New()
+	app.Use(subjectFromHeader)
+	app.Put("/documents/{id}", func(ctx iris.Context) {
+		ok, err := c.Authorizer(ctx).Can(ctx.Params().Get("id"), "write")
+		if err != nil {
+			ctx.StopWithError(iris.StatusInternalServerError, err)
+			return
+		}
+
+		if !ok {
+			ctx.StopWithStatus(iris.StatusForbidden)
+			return
+		}
+
+		ctx.WriteString("updated")
+	})
+
+	m := mvc.New(app.Party("/documents"))
+	m.Register(c.Authorizer)
+	m.Handle(new(documentsController))
+
+	e := httptest.New(t, app)
+	e.PUT("/documents/doc1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK).Body().IsEqual("updated")
+	e.PUT("/documents/doc2").WithHeader("X-User", "alice").Expect().Status(httptest.StatusForbidden)
+
+	e.GET("/documents").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK).
+		JSON().IsEqual([]resourceDocument{{"doc1"}, {"doc3"}})
+	e.GET("/documents").WithHeader("X-User", "carol").Expect().Status(httptest.StatusOK).
+		JSON().IsEqual([]resourceDocument{})
+
+	e.GET("/documents/doc2").WithHeader("X-User", "bob").Expect().Status(httptest.StatusOK).
+		JSON().IsEqual([]string{"read", "delete"})
+
+	ctx := app.ContextPool.Acquire(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	defer app.ContextPool.Release(ctx)
+	SetSubject(ctx, "alice")
+
+	actions, err := c.Authorizer(ctx).AllowedActions("doc1", "read", "delete", "write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"read", "write"}; !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("expected %v but got %v", expected, actions)
+	}
+
+	if _, err = c.Authorizer(ctx).Can("doc1"); err == nil || !strings.Contains(err.Error(), "invalid request size") {
+		t.Fatalf("expected an invalid request size error but got %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+package casbin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	stringadapter "github.com/casbin/casbin/v2/persist/string-adapter"
+)
+
+// PolicyOp is the operation of a PolicyChange.
+type PolicyOp string
+
+// The policy operations.
+const (
+	// PolicyReload reloads the whole policy from the adapter.
+	PolicyReload PolicyOp = "reload"
+	// PolicyAdd adds the Rules.
+	PolicyAdd PolicyOp = "add"
+	// PolicyRemove removes the Rules.
+	PolicyRemove PolicyOp = "remove"
+	// PolicyRemoveFiltered removes the rules which match the FieldValues starting from the FieldIndex.
+	PolicyRemoveFiltered PolicyOp = "remove_filtered"
+	// PolicyUpdate replaces the Rules with the NewRules.
+	PolicyUpdate PolicyOp = "update"
+)
+
+// PolicyChange describes a policy change of a Casbin middleware instance,
+// it is sent to the other instances through a Watcher.
+type PolicyChange struct {
+	Op          PolicyOp   `json:"op"`
+	Sec         string     `json:"sec,omitempty"`
+	PType       string     `json:"ptype,omitempty"`
+	Rules       [][]string `json:"rules,omitempty"`
+	NewRules    [][]string `json:"new_rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+	// Origin is the ID of the instance which made the change,
+	// instances ignore their own changes.
+	Origin string `json:"origin,omitempty"`
+}
+
+// Watcher delivers the policy changes between the instances of a Casbin middleware,
+// e.g. the replicas of an application. See the Casbin.Watch method.
+type Watcher interface {
+	// Publish sends a change to all the subscribers, including the publisher's ones.
+	Publish(ctx context.Context, change PolicyChange) error
+	// Subscribe registers a function which is called on each published change.
+	Subscribe(fn func(PolicyChange)) error
+	// Close stops the delivery of the changes.
+	Close() error
+}
+
+// LocalWatcher is an in-process Watcher, e.g. for tests or for
+// multiple Casbin middleware instances of the same enforcer's policy in a single process.
+// Changes are delivered in order by a single goroutine.
+type LocalWatcher struct {
+	mu          sync.Mutex
+	subscribers []func(PolicyChange)
+	queue       []PolicyChange
+	closed      bool
+
+	signal chan struct{}
+	done   chan struct{}
+}
+
+var _ Watcher = (*LocalWatcher)(nil)
+
+// NewLocalWatcher returns a new in-process Watcher.
+func NewLocalWatcher() *LocalWatcher {
+	w := &LocalWatcher{
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	go w.run()
+	return w
+}
+
+func (w *LocalWatcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.signal:
+		}
+
+		w.mu.Lock()
+		queue, subscribers := w.queue, w.subscribers
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, change := range queue {
+			for _, fn := range subscribers {
+				fn(change)
+			}
+		}
+	}
+}
+
+// Publish queues the change for the subscribers.
+func (w *LocalWatcher) Publish(_ context.Context, change PolicyChange) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return errors.New("casbin: watcher closed")
+	}
+	w.queue = append(w.queue, change)
+	w.mu.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Subscribe registers a function which is called on each published change.
+func (w *LocalWatcher) Subscribe(fn func(PolicyChange)) error {
+	w.mu.Lock()
+	w.subscribers = append(w.subscribers, fn)
+	w.mu.Unlock()
+	return nil
+}
+
+// Close stops the delivery of the changes.
+func (w *LocalWatcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.done)
+	}
+
+	return nil
+}
+
+// Watch publishes the policy changes made through the enforcer of "c", e.g. by the PolicyAPI,
+// to the "w" Watcher and applies the changes of the other instances incrementally.
+// The "onError" optional function is called when a change cannot be applied,
+// in which case the whole policy is reloaded.
+//
+// Usage:
+//
+//	watcher, err := pgadapter.NewWatcher(db, pgadapter.DefaultChannel, nil)
+//	err = casbinMiddleware.Watch(watcher, func(err error) { app.Logger().Error(err) })
+func (c *Casbin) Watch(w Watcher, onError func(error)) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	bridge := &watcherBridge{watcher: w, origin: hex.EncodeToString(id)}
+
+	c.mu.Lock()
+	err := c.enforcer.SetWatcher(bridge)
+	if err == nil {
+		c.watcher = bridge
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return w.Subscribe(func(change PolicyChange) {
+		if change.Origin == bridge.origin {
+			return
+		}
+
+		if err := c.applyChange(change); err != nil {
+			if onError != nil {
+				onError(err)
+			}
+
+			if change.Op != PolicyReload {
+				if err = c.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	})
+}
+
+// applyChange applies a change of another instance to the enforcer's policy.
+// The change is not persisted through the adapter, as it is already persisted by the other instance,
+// and it is not published again.
+func (c *Casbin) applyChange(change PolicyChange) error {
+	if change.Op == PolicyReload {
+		return c.Reload()
+	}
+
+	c.lockPolicy()
+	defer c.unlockPolicy()
+
+	m := c.enforcer.GetModel()
+	if _, ok := m[change.Sec][change.PType]; !ok {
+		return fmt.Errorf("casbin: watcher: unknown policy type: %s.%s", change.Sec, change.PType)
+	}
+
+	var added, removed [][]string
+	switch change.Op {
+	case PolicyAdd:
+		affected, err := m.AddPoliciesWithAffected(change.Sec, change.PType, change.Rules)
+		if err != nil {
+			return err
+		}
+		added = affected
+	case PolicyRemove:
+		affected, err := m.RemovePoliciesWithAffected(change.Sec, change.PType, change.Rules)
+		if err != nil {
+			return err
+		}
+		removed = affected
+	case PolicyRemoveFiltered:
+		_, affected, err := m.RemoveFilteredPolicy(change.Sec, change.PType, change.FieldIndex, change.FieldValues...)
+		if err != nil {
+			return err
+		}
+		removed = affected
+	case PolicyUpdate:
+		// in place, so the order of the rules, e.g. their priority, is kept.
+		if len(change.Rules) == len(change.NewRules) {
+			updated, err := m.UpdatePolicies(change.Sec, change.PType, change.Rules, change.NewRules)
+			if err != nil {
+				return err
+			}
+
+			if updated {
+				removed, added = change.Rules, change.NewRules
+				break
+			}
+		}
+
+		affected, err := m.RemovePoliciesWithAffected(change.Sec, change.PType, change.Rules)
+		if err != nil {
+			return err
+		}
+		removed = affected
+
+		if affected, err = m.AddPoliciesWithAffected(change.Sec, change.PType, change.NewRules); err != nil {
+			return err
+		}
+		added = affected
+	default:
+		return fmt.Errorf("casbin: watcher: unknown operation: %q", change.Op)
+	}
+
+	if change.Sec != "g" {
+		return nil
+	}
+
+	if len(removed) > 0 {
+		if err := c.enforcer.BuildIncrementalRoleLinks(model.PolicyRemove, change.PType, removed); err != nil {
+			return err
+		}
+	}
+
+	if len(added) > 0 {
+		if err := c.enforcer.BuildIncrementalRoleLinks(model.PolicyAdd, change.PType, added); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changePolicy makes a change of the PolicyAPI: it persists the change through the enforcer's adapter,
+// applies it to the enforcer's policy and publishes it to the Watcher, see Watch.
+// The Enforce calls are blocked only while the change is applied, not during the adapter's and the watcher's I/O.
+// Like the enforcer's batch methods, it reports false, without changing the policy,
+// if any of the added rules exists or none of the removed or updated rules exists.
+// The change's operation must be PolicyAdd, PolicyRemove or PolicyUpdate.
+func (c *Casbin) changePolicy(change PolicyChange) (bool, error) {
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	c.mu.RLock()
+	exists, err := c.enforcer.GetModel().HasPolicies(change.Sec, change.PType, change.Rules)
+	adapter, bridge := c.enforcer.GetAdapter(), c.watcher
+	c.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+
+	if (change.Op == PolicyAdd && exists) || (change.Op != PolicyAdd && !exists) {
+		return false, nil
+	}
+
+	if adapter != nil && !readOnlyAdapter(adapter) {
+		if err = persistChange(adapter, change); err != nil {
+			return false, err
+		}
+	}
+
+	if err = c.applyChange(change); err != nil {
+		return false, err
+	}
+
+	if bridge != nil {
+		return true, bridge.publish(change)
+	}
+
+	return true, nil
+}
+
+// readOnlyAdapter reports whether the "adapter" is one of casbin's file or string adapters,
+// which do not implement the rule operations, e.g. AddPolicy, and save the whole policy only.
+func readOnlyAdapter(adapter persist.Adapter) bool {
+	switch adapter.(type) {
+	case *fileadapter.Adapter, *fileadapter.FilteredAdapter, *stringadapter.Adapter:
+		return true
+	default:
+		return false
+	}
+}
+
+// persistChange saves a change through the "adapter", see readOnlyAdapter.
+func persistChange(adapter persist.Adapter, change PolicyChange) error {
+	var err error
+	switch change.Op {
+	case PolicyAdd:
+		if a, ok := adapter.(persist.BatchAdapter); ok {
+			err = a.AddPolicies(change.Sec, change.PType, change.Rules)
+			break
+		}
+
+		for _, rule := range change.Rules {
+			if err = adapter.AddPolicy(change.Sec, change.PType, rule); err != nil {
+				break
+			}
+		}
+	case PolicyRemove:
+		if a, ok := adapter.(persist.BatchAdapter); ok {
+			err = a.RemovePolicies(change.Sec, change.PType, change.Rules)
+			break
+		}
+
+		for _, rule := range change.Rules {
+			if err = adapter.RemovePolicy(change.Sec, change.PType, rule); err != nil {
+				break
+			}
+		}
+	case PolicyUpdate:
+		if a, ok := adapter.(persist.UpdatableAdapter); ok {
+			err = a.UpdatePolicies(change.Sec, change.PType, change.Rules, change.NewRules)
+			break
+		}
+
+		if err = persistChange(adapter, PolicyChange{Op: PolicyRemove, Sec: change.Sec, PType: change.PType, Rules: change.Rules}); err == nil {
+			err = persistChange(adapter, PolicyChange{Op: PolicyAdd, Sec: change.Sec, PType: change.PType, Rules: change.NewRules})
+		}
+	}
+
+	return err
+}
+
+// Reload loads the whole policy from the enforcer's adapter.
+// The policy is read without blocking the Enforce calls,
+// which are only blocked while the new policy is applied.
+// The PolicyAPI's changes wait for the reload, so they are not overwritten by an older policy.
+func (c *Casbin) Reload() error {
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	c.mu.RLock()
+	newModel := c.enforcer.GetModel().Copy()
+	adapter := c.enforcer.GetAdapter()
+	c.mu.RUnlock()
+
+	if adapter == nil {
+		return errors.New("casbin: reload: no adapter")
+	}
+
+	newModel.ClearPolicy()
+	if err := adapter.LoadPolicy(newModel); err != nil {
+		return err
+	}
+
+	if err := newModel.SortPoliciesBySubjectHierarchy(); err != nil {
+		return err
+	}
+
+	if err := newModel.SortPoliciesByPriority(); err != nil {
+		return err
+	}
+
+	c.lockPolicy()
+	defer c.unlockPolicy()
+
+	// keep the enforcer's model, functions and role managers, replace the policy only.
+	m := c.enforcer.GetModel()
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range newModel[sec] {
+			if current, ok := m[sec][ptype]; ok {
+				current.Policy = ast.Policy
+				current.PolicyMap = ast.PolicyMap
+			}
+		}
+	}
+
+	if _, ok := m["g"]; !ok {
+		return nil
+	}
+
+	return c.enforcer.BuildRoleLinks()
+}
+
+// watcherBridge is the enforcer's watcher which publishes its changes.
+type watcherBridge struct {
+	watcher Watcher
+	origin  string
+}
+
+var (
+	_ persist.WatcherEx        = (*watcherBridge)(nil)
+	_ persist.UpdatableWatcher = (*watcherBridge)(nil)
+)
+
+func (b *watcherBridge) publish(change PolicyChange) error {
+	change.Origin = b.origin
+	return b.watcher.Publish(context.Background(), change)
+}
+
+func (b *watcherBridge) SetUpdateCallback(func(string)) error {
+	return nil
+}
+
+func (b *watcherBridge) Update() error {
+	return b.publish(PolicyChange{Op: PolicyReload})
+}
+
+func (b *watcherBridge) Close() {}
+
+func (b *watcherBridge) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return b.UpdateForAddPolicies(sec, ptype, params)
+}
+
+func (b *watcherBridge) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return b.UpdateForRemovePolicies(sec, ptype, params)
+}
+
+func (b *watcherBridge) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return b.publish(PolicyChange{Op: PolicyRemoveFiltered, Sec: sec, PType: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+func (b *watcherBridge) UpdateForSavePolicy(model.Model) error {
+	return b.Update()
+}
+
+func (b *watcherBridge) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return b.publish(PolicyChange{Op: PolicyAdd, Sec: sec, PType: ptype, Rules: rules})
+}
+
+func (b *watcherBridge) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return b.publish(PolicyChange{Op: PolicyRemove, Sec: sec, PType: ptype, Rules: rules})
+}
+
+func (b *watcherBridge) UpdateForUpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return b.UpdateForUpdatePolicies(sec, ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+func (b *watcherBridge) UpdateForUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return b.publish(PolicyChange{Op: PolicyUpdate, Sec: sec, PType: ptype, Rules: oldRules, NewRules: newRules})
+}
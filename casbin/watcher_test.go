@@ -0,0 +1,327 @@
+package casbin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+)
+
+// eventually waits for the watcher's asynchronous delivery.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	w := NewLocalWatcher()
+	defer w.Close()
+
+	var instances []*Casbin
+	for i := 0; i < 2; i++ {
+		c := New(newTestEnforcer(t, rbacModel))
+		if err := c.Watch(w, func(err error) { t.Error(err) }); err != nil {
+			t.Fatal(err)
+		}
+		instances = append(instances, c)
+	}
+	primary, replica := instances[0].enforcer, instances[1]
+
+	enforce := func(sub, obj, act string) func() bool {
+		return func() bool {
+			replica.mu.RLock()
+			ok, err := replica.enforcer.Enforce(sub, obj, act)
+			replica.mu.RUnlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ok
+		}
+	}
+	not := func(cond func() bool) func() bool {
+		return func() bool { return !cond() }
+	}
+
+	mustOK(t)(primary.AddPolicies([][]string{{"reader", "/data", "GET"}, {"writer", "/data", "POST"}}))
+	mustOK(t)(primary.AddGroupingPolicy("alice", "reader"))
+	eventually(t, enforce("alice", "/data", "GET"))
+
+	mustOK(t)(primary.UpdateGroupingPolicy([]string{"alice", "reader"}, []string{"alice", "writer"}))
+	eventually(t, not(enforce("alice", "/data", "GET")))
+	eventually(t, enforce("alice", "/data", "POST"))
+
+	mustOK(t)(primary.RemoveFilteredPolicy(0, "writer"))
+	eventually(t, not(enforce("alice", "/data", "POST")))
+
+	mustOK(t)(primary.RemovePolicy("reader", "/data", "GET"))
+	eventually(t, func() bool {
+		replica.mu.RLock()
+		policy, _ := replica.enforcer.GetPolicy()
+		replica.mu.RUnlock()
+		return len(policy) == 0
+	})
+}
+
+func TestWatchReload(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policyFile, []byte("p, reader, /data/*, GET\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	newInstance := func() *Casbin {
+		e := newTestEnforcer(t, rbacModel)
+		e.SetAdapter(fileadapter.NewAdapter(policyFile))
+		if err := e.LoadPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		return New(e)
+	}
+
+	w := NewLocalWatcher()
+	defer w.Close()
+
+	primary, replica := newInstance(), newInstance()
+	for _, c := range []*Casbin{primary, replica} {
+		if err := c.Watch(w, func(err error) { t.Error(err) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := iris.New()
+	app.Get("/data/{id}", subjectFromHeader, replica.ServeHTTP, func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+	e := httptest.New(t, app)
+
+	// enforce while reloading.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					e.GET("/data/1").WithHeader("X-User", "reader").Expect().Status(httptest.StatusOK)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := replica.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	// SavePolicy publishes a reload.
+	mustOK(t)(primary.enforcer.AddGroupingPolicy("alice", "reader"))
+	if err := primary.enforcer.SavePolicy(); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		replica.mu.RLock()
+		ok, _ := replica.enforcer.Enforce("alice", "/data/1", "GET")
+		replica.mu.RUnlock()
+		return ok
+	})
+	e.GET("/data/1").WithHeader("X-User", "alice").Expect().Status(httptest.StatusOK)
+}
+
+// blockingAdapter blocks its AddPolicy calls until the "release" channel is closed.
+type blockingAdapter struct {
+	persist.Adapter
+	adding  chan []string
+	release chan struct{}
+}
+
+func (a *blockingAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	a.adding <- rule
+	<-a.release
+	return nil
+}
+
+func TestChangePolicyOutsideLock(t *testing.T) {
+	w := NewLocalWatcher()
+	defer w.Close()
+
+	primary, replica := New(newTestEnforcer(t, rbacModel)), New(newTestEnforcer(t, rbacModel))
+	for _, c := range []*Casbin{primary, replica} {
+		if err := c.Watch(w, func(err error) { t.Error(err) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	adapter := &blockingAdapter{adding: make(chan []string), release: make(chan struct{})}
+	primary.enforcer.SetAdapter(adapter)
+
+	change := PolicyChange{Op: PolicyAdd, Sec: "p", PType: "p", Rules: [][]string{{"reader", "/data", "GET"}}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := primary.changePolicy(change)
+		done <- err
+	}()
+
+	<-adapter.adding
+	if !primary.mu.TryRLock() {
+		t.Fatal("expected the enforcer's policy not to be locked while the adapter persists a change")
+	}
+	ok, err := primary.enforcer.Enforce("reader", "/data", "GET")
+	primary.mu.RUnlock()
+	if err != nil || ok {
+		t.Fatalf("expected the change not to be applied before it is persisted: %v", err)
+	}
+
+	close(adapter.release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mustOK(t)(primary.enforcer.Enforce("reader", "/data", "GET"))
+	eventually(t, func() bool {
+		replica.mu.RLock()
+		ok, _ := replica.enforcer.Enforce("reader", "/data", "GET")
+		replica.mu.RUnlock()
+		return ok
+	})
+
+	// existing rules are not persisted again.
+	if ok, err = primary.changePolicy(change); err != nil || ok {
+		t.Fatalf("expected an existing rule not to be added: %v", err)
+	}
+}
+
+// snapshotAdapter stores the rules in memory,
+// its LoadPolicy calls read the rules and block until the "release" channel is closed.
+type snapshotAdapter struct {
+	persist.Adapter
+
+	mu      sync.Mutex
+	rules   [][]string // ptype and values.
+	loading chan struct{}
+	release chan struct{}
+}
+
+func (a *snapshotAdapter) LoadPolicy(m model.Model) error {
+	a.mu.Lock()
+	rules := append([][]string(nil), a.rules...)
+	a.mu.Unlock()
+
+	a.loading <- struct{}{}
+	<-a.release
+
+	for _, rule := range rules {
+		if err := persist.LoadPolicyArray(rule, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *snapshotAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	a.mu.Lock()
+	a.rules = append(a.rules, append([]string{ptype}, rule...))
+	a.mu.Unlock()
+	return nil
+}
+
+func TestReloadConcurrentChange(t *testing.T) {
+	c := New(newTestEnforcer(t, rbacModel))
+	adapter := &snapshotAdapter{
+		rules:   [][]string{{"p", "reader", "/data/*", "GET"}},
+		loading: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	c.enforcer.SetAdapter(adapter)
+
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- c.Reload() }()
+	<-adapter.loading
+
+	changed := make(chan error, 1)
+	go func() {
+		_, err := c.changePolicy(PolicyChange{Op: PolicyAdd, Sec: "p", PType: "p", Rules: [][]string{{"writer", "/data/*", "POST"}}})
+		changed <- err
+	}()
+
+	select {
+	case <-changed:
+		t.Fatal("expected the change to wait for the reload")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(adapter.release)
+	for _, done := range []chan error{reloaded, changed} {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustOK(t)(c.enforcer.Enforce("reader", "/data/1", "GET"))
+	mustOK(t)(c.enforcer.Enforce("writer", "/data/1", "POST"))
+}
+
+// failingAdapter fails its AddPolicy calls.
+type failingAdapter struct {
+	persist.Adapter
+}
+
+func (failingAdapter) AddPolicy(string, string, []string) error {
+	return errors.New("not implemented")
+}
+
+func TestChangePolicyAdapterError(t *testing.T) {
+	c := New(newTestEnforcer(t, rbacModel))
+	c.enforcer.SetAdapter(failingAdapter{})
+
+	change := PolicyChange{Op: PolicyAdd, Sec: "p", PType: "p", Rules: [][]string{{"writer", "/data/*", "POST"}}}
+	if _, err := c.changePolicy(change); err == nil {
+		t.Fatal("expected the adapter's error")
+	}
+
+	if ok, _ := c.enforcer.Enforce("writer", "/data/1", "POST"); ok {
+		t.Fatal("expected a change which is not persisted not to be applied")
+	}
+
+	// casbin's file adapter does not save single rules.
+	c.enforcer.SetAdapter(fileadapter.NewAdapter(filepath.Join(t.TempDir(), "policy.csv")))
+	mustOK(t)(c.changePolicy(change))
+	mustOK(t)(c.enforcer.Enforce("writer", "/data/1", "POST"))
+}
+
+func mustOK(t *testing.T) func(bool, error) {
+	t.Helper()
+
+	return func(ok bool, err error) {
+		t.Helper()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Fatal("expected true")
+		}
+	}
+}
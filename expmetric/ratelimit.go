@@ -0,0 +1,218 @@
+package expmetric
+
+import (
+	"container/heap"
+	"expvar"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/kataras/iris/v12"
+)
+
+// DefaultRateLimitMaxKeys is the default number of keys a RateLimitStats tracks.
+const DefaultRateLimitMaxKeys = 1000
+
+// Decision labels of a RateLimitCounter, see RecordDecision.
+const (
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+)
+
+// RateLimitStats counts the rate limiting decisions by route, policy and decision
+// and tracks the most limited keys. It can be published to expvar (see Publish)
+// and served as a debug endpoint (see Handler).
+//
+// It records the decisions of the throttler and tollboothic middlewares through their Record function:
+//
+//	stats := expmetric.NewRateLimitStats(0)
+//	stats.Publish("ratelimit")
+//	limiter.Observers = []throttler.Observer{throttler.Record(stats)}
+type RateLimitStats struct {
+	maxKeys int
+
+	mu       sync.Mutex
+	counters map[rateLimitCounterKey]int64
+	limited  map[string]*keyCountItem
+	// least orders the limited keys by their counts, least limited first.
+	least keyCountHeap
+}
+
+type rateLimitCounterKey struct {
+	route, policy, decision string
+}
+
+// RateLimitCounter is the number of decisions of a route and policy.
+type RateLimitCounter struct {
+	Route    string `json:"route"`
+	Policy   string `json:"policy"`
+	Decision string `json:"decision"`
+	Count    int64  `json:"count"`
+}
+
+// KeyCount is the number of limited requests of a key.
+// The count of a key which was evicted and tracked again includes an error margin.
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// NewRateLimitStats returns a new RateLimitStats which tracks up to "maxKeys" limited keys,
+// the least limited key is replaced by a new one when the limit is reached.
+// If "maxKeys" is zero or negative, DefaultRateLimitMaxKeys is used.
+func NewRateLimitStats(maxKeys int) *RateLimitStats {
+	if maxKeys <= 0 {
+		maxKeys = DefaultRateLimitMaxKeys
+	}
+
+	return &RateLimitStats{
+		maxKeys:  maxKeys,
+		counters: make(map[rateLimitCounterKey]int64),
+		limited:  make(map[string]*keyCountItem),
+	}
+}
+
+// RecordDecision counts a rate limiting decision. The "decision" is DecisionAllowed,
+// DecisionDenied or another label of a limited request, e.g. "shadow_denied".
+func (s *RateLimitStats) RecordDecision(route, policy, key, decision string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counters[rateLimitCounterKey{route, policy, decision}]++
+
+	if decision == DecisionAllowed {
+		return
+	}
+
+	if item, ok := s.limited[key]; ok {
+		item.Count++
+		heap.Fix(&s.least, item.index)
+		return
+	}
+
+	if len(s.limited) < s.maxKeys {
+		item := &keyCountItem{KeyCount: KeyCount{Key: key, Count: 1}}
+		s.limited[key] = item
+		heap.Push(&s.least, item)
+		return
+	}
+
+	// Replace the least limited key, its count is the error margin of the new one (Space-Saving algorithm).
+	item := s.least[0]
+	delete(s.limited, item.Key)
+	item.Key = key
+	item.Count++
+	s.limited[key] = item
+	heap.Fix(&s.least, 0)
+}
+
+// Counters returns the decision counters, sorted by route, policy and decision.
+func (s *RateLimitStats) Counters() []RateLimitCounter {
+	s.mu.Lock()
+	counters := make([]RateLimitCounter, 0, len(s.counters))
+	for k, count := range s.counters {
+		counters = append(counters, RateLimitCounter{Route: k.route, Policy: k.policy, Decision: k.decision, Count: count})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(counters, func(i, j int) bool {
+		a, b := counters[i], counters[j]
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		return a.Decision < b.Decision
+	})
+
+	return counters
+}
+
+// TopKeys returns up to "n" of the most limited keys, most limited first.
+func (s *RateLimitStats) TopKeys(n int) []KeyCount {
+	s.mu.Lock()
+	keys := make([]KeyCount, 0, len(s.limited))
+	for _, item := range s.limited {
+		keys = append(keys, item.KeyCount)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+
+	if n >= 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+
+	return keys
+}
+
+type rateLimitSnapshot struct {
+	Counters []RateLimitCounter `json:"counters"`
+	TopKeys  []KeyCount         `json:"topKeys"`
+}
+
+func (s *RateLimitStats) snapshot(n int) rateLimitSnapshot {
+	return rateLimitSnapshot{Counters: s.Counters(), TopKeys: s.TopKeys(n)}
+}
+
+// Publish publishes the counters and the top 10 limited keys to expvar under the "name",
+// so they are served by the expvar Handler.
+// Like expvar.Publish, it panics if the name is already registered.
+func (s *RateLimitStats) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return s.snapshot(10)
+	}))
+}
+
+// Handler is a debug endpoint which writes the counters and the most limited keys as JSON.
+// The "top" URL query parameter sets the number of keys, default is 10.
+// It should be registered to a protected route.
+func (s *RateLimitStats) Handler(ctx iris.Context) {
+	n := 10
+	if v := ctx.URLParam("top"); v != "" {
+		if top, err := strconv.Atoi(v); err == nil && top >= 0 {
+			n = top
+		}
+	}
+
+	ctx.JSON(s.snapshot(n))
+}
+
+// keyCountItem is a KeyCount of the keyCountHeap.
+type keyCountItem struct {
+	KeyCount
+	index int
+}
+
+// keyCountHeap is a min-heap of the limited keys by their counts, see container/heap.
+type keyCountHeap []*keyCountItem
+
+func (h keyCountHeap) Len() int           { return len(h) }
+func (h keyCountHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+
+func (h keyCountHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *keyCountHeap) Push(x any) {
+	item := x.(*keyCountItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *keyCountHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
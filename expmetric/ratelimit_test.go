@@ -0,0 +1,66 @@
+package expmetric
+
+import (
+	"expvar"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+)
+
+func TestRateLimitStats(t *testing.T) {
+	stats := NewRateLimitStats(2)
+
+	stats.RecordDecision("GET/", "default", "a", DecisionAllowed)
+	for key, n := range map[string]int{"a": 3, "b": 2} {
+		for range n {
+			stats.RecordDecision("GET/", "default", key, DecisionDenied)
+		}
+	}
+	// "c" replaces "b", the least limited key, with an error margin of 2.
+	stats.RecordDecision("GET/users", "hour", "c", DecisionDenied)
+
+	expectedCounters := []RateLimitCounter{
+		{Route: "GET/", Policy: "default", Decision: DecisionAllowed, Count: 1},
+		{Route: "GET/", Policy: "default", Decision: DecisionDenied, Count: 5},
+		{Route: "GET/users", Policy: "hour", Decision: DecisionDenied, Count: 1},
+	}
+	if counters := stats.Counters(); fmt.Sprint(counters) != fmt.Sprint(expectedCounters) {
+		t.Fatalf("expected counters %v but got %v", expectedCounters, counters)
+	}
+
+	expectedKeys := []KeyCount{{Key: "a", Count: 3}, {Key: "c", Count: 3}}
+	if keys := stats.TopKeys(-1); fmt.Sprint(keys) != fmt.Sprint(expectedKeys) {
+		t.Fatalf("expected top keys %v but got %v", expectedKeys, keys)
+	}
+
+	stats.RecordDecision("GET/", "default", "a", DecisionDenied)
+	stats.RecordDecision("GET/", "default", "d", DecisionDenied)
+	expectedKeys = []KeyCount{{Key: "a", Count: 4}, {Key: "d", Count: 4}}
+	if keys := stats.TopKeys(10); fmt.Sprint(keys) != fmt.Sprint(expectedKeys) {
+		t.Fatalf("expected top keys %v but got %v", expectedKeys, keys)
+	}
+
+	stats.Publish("test-ratelimit")
+	if v := expvar.Get("test-ratelimit"); v == nil {
+		t.Fatal("expected the stats to be published")
+	}
+
+	app := iris.New()
+	app.Get("/debug/ratelimit", stats.Handler)
+
+	e := httptest.New(t, app)
+	e.GET("/debug/ratelimit").WithQuery("top", 1).Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("topKeys").Array().Length().IsEqual(1)
+}
+
+func BenchmarkRateLimitStats(b *testing.B) {
+	stats := NewRateLimitStats(DefaultRateLimitMaxKeys)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stats.RecordDecision("GET/", "default", "key"+strconv.Itoa(i%(2*DefaultRateLimitMaxKeys)), DecisionDenied)
+	}
+}
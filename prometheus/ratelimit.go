@@ -0,0 +1,53 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const rateLimitDecisionsName = "ratelimit_decisions_total"
+
+// RateLimit counts the rate limiting decisions, partitioned by route, policy and decision, e.g. "allowed" or "denied".
+//
+// It records the decisions of the throttler and tollboothic middlewares through their Record function:
+//
+//	limiter.Observers = []throttler.Observer{throttler.Record(prometheus.NewRateLimit("api"))}
+type RateLimit struct {
+	decisions *prometheus.CounterVec
+}
+
+// NewRateLimit returns a new RateLimit which registers its counters to the default Prometheus registerer.
+// It panics if the counters are already registered.
+func NewRateLimit(name string) *RateLimit {
+	r, err := NewRateLimitWithRegisterer(name, prometheus.DefaultRegisterer)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// NewRateLimitWithRegisterer returns a new RateLimit which registers its counters to the "registerer".
+// The "name" is the value of the "service" constant label.
+func NewRateLimitWithRegisterer(name string, registerer prometheus.Registerer) (*RateLimit, error) {
+	r := &RateLimit{
+		decisions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        rateLimitDecisionsName,
+				Help:        "How many rate limiting decisions were made, partitioned by route, policy and decision.",
+				ConstLabels: prometheus.Labels{"service": name},
+			},
+			[]string{"route", "policy", "decision"},
+		),
+	}
+
+	if err := registerer.Register(r.decisions); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// RecordDecision counts a rate limiting decision, the key is not used as a label.
+func (r *RateLimit) RecordDecision(route, policy, _, decision string) {
+	r.decisions.WithLabelValues(route, policy, decision).Inc()
+}
@@ -0,0 +1,32 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRateLimit(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	r, err := NewRateLimitWithRegisterer("test", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.RecordDecision("GET/", "default", "alice", "denied")
+	r.RecordDecision("GET/", "default", "alice", "allowed")
+	r.RecordDecision("GET/", "default", "bob", "allowed")
+
+	if n := testutil.ToFloat64(r.decisions.WithLabelValues("GET/", "default", "allowed")); n != 2 {
+		t.Errorf("expected 2 allowed decisions but got %v", n)
+	}
+
+	if n := testutil.ToFloat64(r.decisions.WithLabelValues("GET/", "default", "denied")); n != 1 {
+		t.Errorf("expected 1 denied decision but got %v", n)
+	}
+
+	if _, err = NewRateLimitWithRegisterer("test", registry); err == nil {
+		t.Errorf("expected an error for duplicate registration")
+	}
+}
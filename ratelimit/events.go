@@ -0,0 +1,91 @@
+// Package ratelimit provides the rate limiting decision events
+// of the throttler and tollboothic middlewares.
+package ratelimit
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+// Decision labels of an Event.
+const (
+	DecisionAllowed      = "allowed"
+	DecisionDenied       = "denied"
+	DecisionShadowDenied = "shadow_denied"
+)
+
+// Event describes a rate limiting decision, see the Observer interface.
+type Event struct {
+	// Route is the name of the current route, e.g. "GET/users/{id:uint64}".
+	Route string
+	// Policy is the name of the limit the decision refers to.
+	Policy string
+	// Key is the rate limiting key of the request.
+	Key string
+	// Limited reports whether the request exceeded the limit.
+	Limited bool
+	// Shadow reports whether the decision was made in shadow mode,
+	// so a limited request was not denied.
+	Shadow bool
+}
+
+// Decision returns the label of the event's decision: DecisionAllowed,
+// DecisionDenied or DecisionShadowDenied.
+func (e Event) Decision() string {
+	switch {
+	case !e.Limited:
+		return DecisionAllowed
+	case e.Shadow:
+		return DecisionShadowDenied
+	default:
+		return DecisionDenied
+	}
+}
+
+// Observer is notified of the rate limiting decisions, e.g. a DecisionRecorder (see Record).
+// Observers are called synchronously and must be safe for concurrent use.
+type Observer interface {
+	Observe(ctx iris.Context, event Event)
+}
+
+// ObserverFunc is an Observer function.
+type ObserverFunc func(ctx iris.Context, event Event)
+
+// Observe calls f(ctx, event).
+func (f ObserverFunc) Observe(ctx iris.Context, event Event) {
+	f(ctx, event)
+}
+
+// DecisionRecorder records the rate limiting decisions, e.g. the RateLimitStats
+// of the iris-contrib/middleware/expmetric package and the RateLimit counters
+// of the iris-contrib/middleware/prometheus package.
+type DecisionRecorder interface {
+	RecordDecision(route, policy, key, decision string)
+}
+
+// Record returns an Observer which passes the events to the "recorder",
+// the decision is the event's Decision label.
+//
+// Usage:
+//
+//	stats := expmetric.NewRateLimitStats(0)
+//	limiter.Observers = []throttler.Observer{ratelimit.Record(stats)}
+func Record(recorder DecisionRecorder) Observer {
+	return ObserverFunc(func(_ iris.Context, event Event) {
+		recorder.RecordDecision(event.Route, event.Policy, event.Key, event.Decision())
+	})
+}
+
+// Notify sets the name of the current route to the "event" and passes it to the "observers".
+func Notify(observers []Observer, ctx iris.Context, event Event) {
+	if len(observers) == 0 {
+		return
+	}
+
+	if route := ctx.GetCurrentRoute(); route != nil {
+		event.Route = route.Name()
+	}
+
+	for _, observer := range observers {
+		observer.Observe(ctx, event)
+	}
+}
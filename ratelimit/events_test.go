@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+type decisionRecorder [][]string
+
+func (r *decisionRecorder) RecordDecision(route, policy, key, decision string) {
+	*r = append(*r, []string{route, policy, key, decision})
+}
+
+func TestRecord(t *testing.T) {
+	var recorded decisionRecorder
+	observers := []Observer{Record(&recorded)}
+
+	app := iris.New()
+	app.Get("/", func(ctx iris.Context) {
+		Notify(observers, ctx, Event{Policy: "default", Key: "a"})
+		Notify(observers, ctx, Event{Policy: "default", Key: "a", Limited: true})
+		Notify(observers, ctx, Event{Policy: "minute", Key: "b", Limited: true, Shadow: true})
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	expected := decisionRecorder{
+		{"GET/", "default", "a", DecisionAllowed},
+		{"GET/", "default", "a", DecisionDenied},
+		{"GET/", "minute", "b", DecisionShadowDenied},
+	}
+	if !reflect.DeepEqual(recorded, expected) {
+		t.Fatalf("expected decisions %q but got %q", expected, recorded)
+	}
+}
@@ -0,0 +1,145 @@
+package secure
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	coopHeader                   = "Cross-Origin-Opener-Policy"
+	coepHeader                   = "Cross-Origin-Embedder-Policy"
+	corpHeader                   = "Cross-Origin-Resource-Policy"
+	permissionsPolicyHeader      = "Permissions-Policy"
+	permittedCrossDomainPolicies = "X-Permitted-Cross-Domain-Policies"
+)
+
+// CrossOriginOpenerPolicy is a value of the Cross-Origin-Opener-Policy header.
+type CrossOriginOpenerPolicy string
+
+// Cross-Origin-Opener-Policy values.
+const (
+	COOPUnsafeNone            CrossOriginOpenerPolicy = "unsafe-none"
+	COOPSameOriginAllowPopups CrossOriginOpenerPolicy = "same-origin-allow-popups"
+	COOPSameOrigin            CrossOriginOpenerPolicy = "same-origin"
+	COOPNoopenerAllowPopups   CrossOriginOpenerPolicy = "noopener-allow-popups"
+)
+
+// CrossOriginEmbedderPolicy is a value of the Cross-Origin-Embedder-Policy header.
+type CrossOriginEmbedderPolicy string
+
+// Cross-Origin-Embedder-Policy values.
+const (
+	COEPUnsafeNone     CrossOriginEmbedderPolicy = "unsafe-none"
+	COEPRequireCorp    CrossOriginEmbedderPolicy = "require-corp"
+	COEPCredentialless CrossOriginEmbedderPolicy = "credentialless"
+)
+
+// CrossOriginResourcePolicy is a value of the Cross-Origin-Resource-Policy header.
+type CrossOriginResourcePolicy string
+
+// Cross-Origin-Resource-Policy values.
+const (
+	CORPSameSite    CrossOriginResourcePolicy = "same-site"
+	CORPSameOrigin  CrossOriginResourcePolicy = "same-origin"
+	CORPCrossOrigin CrossOriginResourcePolicy = "cross-origin"
+)
+
+// PermittedCrossDomainPolicies is a value of the X-Permitted-Cross-Domain-Policies header,
+// used by Adobe products (e.g. PDF readers) to load data across domains.
+type PermittedCrossDomainPolicies string
+
+// X-Permitted-Cross-Domain-Policies values.
+const (
+	CrossDomainNone             PermittedCrossDomainPolicies = "none"
+	CrossDomainMasterOnly       PermittedCrossDomainPolicies = "master-only"
+	CrossDomainByContentType    PermittedCrossDomainPolicies = "by-content-type"
+	CrossDomainByFTPFilename    PermittedCrossDomainPolicies = "by-ftp-filename"
+	CrossDomainAll              PermittedCrossDomainPolicies = "all"
+	CrossDomainNoneThisResponse PermittedCrossDomainPolicies = "none-this-response"
+)
+
+// Permissions-Policy allowlist special members.
+const (
+	// PermissionsSelf allows the feature for the same origin.
+	PermissionsSelf = "self"
+	// PermissionsAll allows the feature for all origins.
+	PermissionsAll = "*"
+	// PermissionsSrc allows the feature for the iframe's src origin (iframe "allow" attribute only).
+	PermissionsSrc = "src"
+)
+
+// PermissionsPolicy maps browser features (e.g. "camera", "geolocation")
+// to their allowlists. An allowlist holds PermissionsSelf, PermissionsAll
+// or origins like "https://example.com". An empty allowlist disables the feature.
+//
+// Example:
+//
+//	secure.PermissionsPolicy{
+//		"camera":      {},
+//		"geolocation": {secure.PermissionsSelf, "https://maps.example.com"},
+//	}
+//
+// Renders: `camera=(), geolocation=(self "https://maps.example.com")`.
+type PermissionsPolicy map[string][]string
+
+// String returns the Permissions-Policy header value, features are sorted by name.
+func (p PermissionsPolicy) String() string {
+	features := make([]string, 0, len(p))
+	for feature := range p {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	var sb strings.Builder
+	for i, feature := range features {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(feature)
+		sb.WriteByte('=')
+
+		allowlist := p[feature]
+		if len(allowlist) == 1 && allowlist[0] == PermissionsAll {
+			sb.WriteString(PermissionsAll)
+			continue
+		}
+
+		sb.WriteByte('(')
+		for j, member := range allowlist {
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+
+			switch member {
+			case PermissionsSelf, PermissionsAll, PermissionsSrc:
+				sb.WriteString(member)
+			default:
+				sb.WriteByte('"')
+				sb.WriteString(member)
+				sb.WriteByte('"')
+			}
+		}
+		sb.WriteByte(')')
+	}
+
+	return sb.String()
+}
+
+// CrossOriginIsolated returns an OverrideFunc which makes the document cross-origin isolated,
+// enabling features like SharedArrayBuffer and high resolution timers.
+// It sets Cross-Origin-Opener-Policy to same-origin, Cross-Origin-Embedder-Policy to
+// require-corp and Cross-Origin-Resource-Policy to same-origin.
+// Every cross-origin subresource must then opt-in through CORS or its own
+// Cross-Origin-Resource-Policy header.
+//
+// Usage:
+//
+//	secure.New(secure.MergeOptions(opts, secure.CrossOriginIsolated()))
+//	s.OverridePath("/editor", secure.CrossOriginIsolated())
+func CrossOriginIsolated() OverrideFunc {
+	return func(o *Options) {
+		o.CrossOriginOpenerPolicy = COOPSameOrigin
+		o.CrossOriginEmbedderPolicy = COEPRequireCorp
+		o.CrossOriginResourcePolicy = CORPSameOrigin
+	}
+}
@@ -0,0 +1,39 @@
+package secure
+
+import (
+	"testing"
+)
+
+func TestCrossOriginHeaders(t *testing.T) {
+	s := New(MergeOptions(Options{
+		PermissionsPolicy: PermissionsPolicy{
+			"camera":      {},
+			"fullscreen":  {PermissionsAll},
+			"geolocation": {PermissionsSelf, "https://maps.example.com"},
+		},
+		PermittedCrossDomainPolicies: CrossDomainNone,
+	}, CrossOriginIsolated()))
+
+	header, err := s.ProcessNoModifyRequest(newTestContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, header.Get("Permissions-Policy"), `camera=(), fullscreen=*, geolocation=(self "https://maps.example.com")`)
+	expect(t, header.Get("Cross-Origin-Opener-Policy"), "same-origin")
+	expect(t, header.Get("Cross-Origin-Embedder-Policy"), "require-corp")
+	expect(t, header.Get("Cross-Origin-Resource-Policy"), "same-origin")
+	expect(t, header.Get("X-Permitted-Cross-Domain-Policies"), "none")
+}
+
+func TestFeaturePolicyFallback(t *testing.T) {
+	s := New(Options{FeaturePolicy: "camera 'none'"})
+
+	header, err := s.ProcessNoModifyRequest(newTestContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, header.Get("Feature-Policy"), "camera 'none'")
+	expect(t, header.Get("Permissions-Policy"), "")
+}
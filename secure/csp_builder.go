@@ -0,0 +1,631 @@
+package secure
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CSPDirective is a Content-Security-Policy directive name, e.g. "script-src".
+type CSPDirective string
+
+// Fetch, document, navigation and reporting directives supported by the CSPBuilder.
+const (
+	CSPDefaultSrc     CSPDirective = "default-src"
+	CSPScriptSrc      CSPDirective = "script-src"
+	CSPScriptSrcElem  CSPDirective = "script-src-elem"
+	CSPScriptSrcAttr  CSPDirective = "script-src-attr"
+	CSPStyleSrc       CSPDirective = "style-src"
+	CSPStyleSrcElem   CSPDirective = "style-src-elem"
+	CSPStyleSrcAttr   CSPDirective = "style-src-attr"
+	CSPImgSrc         CSPDirective = "img-src"
+	CSPFontSrc        CSPDirective = "font-src"
+	CSPConnectSrc     CSPDirective = "connect-src"
+	CSPMediaSrc       CSPDirective = "media-src"
+	CSPObjectSrc      CSPDirective = "object-src"
+	CSPFrameSrc       CSPDirective = "frame-src"
+	CSPFencedFrameSrc CSPDirective = "fenced-frame-src"
+	CSPChildSrc       CSPDirective = "child-src"
+	CSPWorkerSrc      CSPDirective = "worker-src"
+	CSPManifestSrc    CSPDirective = "manifest-src"
+	CSPBaseURI        CSPDirective = "base-uri"
+	CSPFormAction     CSPDirective = "form-action"
+	CSPFrameAncestors CSPDirective = "frame-ancestors"
+	CSPSandbox        CSPDirective = "sandbox"
+	CSPReportURI      CSPDirective = "report-uri"
+	CSPReportTo       CSPDirective = "report-to"
+	// CSPRequireTrustedTypesFor accepts the "'script'" keyword only.
+	CSPRequireTrustedTypesFor CSPDirective = "require-trusted-types-for"
+	CSPTrustedTypes           CSPDirective = "trusted-types"
+	// CSPUpgradeInsecureRequests does not accept any value.
+	CSPUpgradeInsecureRequests CSPDirective = "upgrade-insecure-requests"
+	// CSPBlockAllMixedContent is deprecated in favor of CSPUpgradeInsecureRequests,
+	// it does not accept any value.
+	CSPBlockAllMixedContent CSPDirective = "block-all-mixed-content"
+)
+
+type cspDirectiveKind uint8
+
+const (
+	cspSourceList  cspDirectiveKind = iota // accepts source expressions.
+	cspTokenList                           // accepts free-form tokens (sandbox flags, policy names, URLs).
+	cspSingleToken                         // accepts exactly one token (report-to group).
+	cspNoValue                             // accepts nothing.
+)
+
+var cspDirectiveKinds = map[CSPDirective]cspDirectiveKind{
+	CSPDefaultSrc:              cspSourceList,
+	CSPScriptSrc:               cspSourceList,
+	CSPScriptSrcElem:           cspSourceList,
+	CSPScriptSrcAttr:           cspSourceList,
+	CSPStyleSrc:                cspSourceList,
+	CSPStyleSrcElem:            cspSourceList,
+	CSPStyleSrcAttr:            cspSourceList,
+	CSPImgSrc:                  cspSourceList,
+	CSPFontSrc:                 cspSourceList,
+	CSPConnectSrc:              cspSourceList,
+	CSPMediaSrc:                cspSourceList,
+	CSPObjectSrc:               cspSourceList,
+	CSPFrameSrc:                cspSourceList,
+	CSPFencedFrameSrc:          cspSourceList,
+	CSPChildSrc:                cspSourceList,
+	CSPWorkerSrc:               cspSourceList,
+	CSPManifestSrc:             cspSourceList,
+	CSPBaseURI:                 cspSourceList,
+	CSPFormAction:              cspSourceList,
+	CSPFrameAncestors:          cspSourceList,
+	CSPSandbox:                 cspTokenList,
+	CSPReportURI:               cspTokenList,
+	CSPReportTo:                cspSingleToken,
+	CSPRequireTrustedTypesFor:  cspTokenList,
+	CSPTrustedTypes:            cspTokenList,
+	CSPUpgradeInsecureRequests: cspNoValue,
+	CSPBlockAllMixedContent:    cspNoValue,
+}
+
+// CSPSource is a single source expression of a CSP directive,
+// e.g. a keyword like CSPSelf, a host like "https://cdn.example.com",
+// a scheme like "data:", a hash (see CSPHash) or the CSPNonceSource placeholder.
+type CSPSource string
+
+// Source keywords. Their values include the required single quotes.
+const (
+	CSPSelf                  CSPSource = "'self'"
+	CSPNone                  CSPSource = "'none'"
+	CSPUnsafeInline          CSPSource = "'unsafe-inline'"
+	CSPUnsafeEval            CSPSource = "'unsafe-eval'"
+	CSPUnsafeHashes          CSPSource = "'unsafe-hashes'"
+	CSPStrictDynamic         CSPSource = "'strict-dynamic'"
+	CSPReportSample          CSPSource = "'report-sample'"
+	CSPWasmUnsafeEval        CSPSource = "'wasm-unsafe-eval'"
+	CSPInlineSpeculationRule CSPSource = "'inline-speculation-rules'"
+	// CSPNonceSource is replaced by `'nonce-<value>'` on each request,
+	// the value can be retrieved through the CSPNonce package-level function.
+	CSPNonceSource CSPSource = "$NONCE"
+)
+
+var cspKeywords = map[CSPSource]struct{}{
+	CSPSelf:                  {},
+	CSPNone:                  {},
+	CSPUnsafeInline:          {},
+	CSPUnsafeEval:            {},
+	CSPUnsafeHashes:          {},
+	CSPStrictDynamic:         {},
+	CSPReportSample:          {},
+	CSPWasmUnsafeEval:        {},
+	CSPInlineSpeculationRule: {},
+}
+
+// Hash algorithms accepted by CSPHash.
+const (
+	CSPSHA256 = "sha256"
+	CSPSHA384 = "sha384"
+	CSPSHA512 = "sha512"
+)
+
+var cspHashSizes = map[string]int{
+	CSPSHA256: sha256.Size,
+	CSPSHA384: sha512.Size384,
+	CSPSHA512: sha512.Size,
+}
+
+// CSPHash computes the digest of an inline script or style "content"
+// and returns its source expression, e.g. `'sha256-<base64>'`.
+// The "algorithm" should be one of CSPSHA256, CSPSHA384 or CSPSHA512,
+// an unknown algorithm results to an invalid source reported by CSPBuilder.Build.
+func CSPHash(algorithm string, content []byte) CSPSource {
+	var sum []byte
+	switch algorithm {
+	case CSPSHA256:
+		b := sha256.Sum256(content)
+		sum = b[:]
+	case CSPSHA384:
+		b := sha512.Sum384(content)
+		sum = b[:]
+	case CSPSHA512:
+		b := sha512.Sum512(content)
+		sum = b[:]
+	default:
+		return CSPSource("'" + algorithm + "-'")
+	}
+
+	return CSPSource("'" + algorithm + "-" + base64.StdEncoding.EncodeToString(sum) + "'")
+}
+
+// CSPBuilder builds a CSPPolicy.
+// Directives are rendered in the order they were first added,
+// adding sources to an existing directive appends to it.
+// Any misconfiguration is reported by its Build method.
+type CSPBuilder struct {
+	order      []CSPDirective
+	directives map[CSPDirective][]CSPSource
+	// raw holds the directives added through AddRaw.
+	raw map[CSPDirective]struct{}
+}
+
+// NewCSPBuilder returns a new, empty, CSPBuilder.
+//
+// Example:
+//
+//	csp, err := secure.NewCSPBuilder().
+//		Add(secure.CSPDefaultSrc, secure.CSPSelf).
+//		Add(secure.CSPScriptSrc, secure.CSPStrictDynamic, secure.CSPNonceSource).
+//		Add(secure.CSPObjectSrc, secure.CSPNone).
+//		ReportTo("csp-endpoint").
+//		Build()
+func NewCSPBuilder() *CSPBuilder {
+	return &CSPBuilder{directives: make(map[CSPDirective][]CSPSource)}
+}
+
+// Add appends the given sources to the "directive".
+func (b *CSPBuilder) Add(directive CSPDirective, sources ...CSPSource) *CSPBuilder {
+	existing, ok := b.directives[directive]
+	if !ok {
+		b.order = append(b.order, directive)
+	}
+
+	b.directives[directive] = append(existing, sources...)
+	return b
+}
+
+// AddRaw same as Add but it accepts directives which the builder does not know,
+// e.g. new or experimental ones. Their names and values are checked for syntax only.
+// Directives which the builder knows are validated as usual.
+func (b *CSPBuilder) AddRaw(directive CSPDirective, values ...CSPSource) *CSPBuilder {
+	if _, known := cspDirectiveKinds[directive]; !known {
+		if b.raw == nil {
+			b.raw = make(map[CSPDirective]struct{})
+		}
+		b.raw[directive] = struct{}{}
+	}
+
+	return b.Add(directive, values...)
+}
+
+// Set replaces the sources of the "directive".
+func (b *CSPBuilder) Set(directive CSPDirective, sources ...CSPSource) *CSPBuilder {
+	if _, ok := b.directives[directive]; ok {
+		b.directives[directive] = nil
+	}
+
+	return b.Add(directive, sources...)
+}
+
+// Remove removes the "directive" from the policy.
+func (b *CSPBuilder) Remove(directive CSPDirective) *CSPBuilder {
+	if _, ok := b.directives[directive]; !ok {
+		return b
+	}
+
+	delete(b.directives, directive)
+	delete(b.raw, directive)
+	for i, d := range b.order {
+		if d == directive {
+			b.order = append(b.order[:i:i], b.order[i+1:]...)
+			break
+		}
+	}
+
+	return b
+}
+
+// ReportTo sets the "report-to" directive to the given Reporting API endpoint group name.
+func (b *CSPBuilder) ReportTo(group string) *CSPBuilder {
+	return b.Set(CSPReportTo, CSPSource(group))
+}
+
+// ReportURI appends the given URIs to the deprecated "report-uri" directive.
+// Prefer ReportTo, although browsers without Reporting API support
+// still need it.
+func (b *CSPBuilder) ReportURI(uris ...string) *CSPBuilder {
+	sources := make([]CSPSource, 0, len(uris))
+	for _, uri := range uris {
+		sources = append(sources, CSPSource(uri))
+	}
+
+	return b.Add(CSPReportURI, sources...)
+}
+
+// UpgradeInsecureRequests adds the "upgrade-insecure-requests" directive.
+func (b *CSPBuilder) UpgradeInsecureRequests() *CSPBuilder {
+	return b.Add(CSPUpgradeInsecureRequests)
+}
+
+// Clone returns a deep copy of the builder,
+// useful to derive a policy from a base one.
+func (b *CSPBuilder) Clone() *CSPBuilder {
+	c := &CSPBuilder{
+		order:      append([]CSPDirective(nil), b.order...),
+		directives: make(map[CSPDirective][]CSPSource, len(b.directives)),
+	}
+	for d, sources := range b.directives {
+		c.directives[d] = append([]CSPSource(nil), sources...)
+	}
+	if b.raw != nil {
+		c.raw = make(map[CSPDirective]struct{}, len(b.raw))
+		for d := range b.raw {
+			c.raw[d] = struct{}{}
+		}
+	}
+
+	return c
+}
+
+// Build validates the directives and renders the policy.
+func (b *CSPBuilder) Build() (*CSPPolicy, error) {
+	return b.build(true)
+}
+
+// build renders the policy, validating the directives if "validate" is true.
+func (b *CSPBuilder) build(validate bool) (*CSPPolicy, error) {
+	if len(b.order) == 0 {
+		return nil, fmt.Errorf("csp: empty policy")
+	}
+
+	var (
+		sb       strings.Builder
+		parts    []string
+		reportTo string
+	)
+
+	for i, directive := range b.order {
+		sources := dedupCSPSources(b.directives[directive])
+		if validate {
+			_, raw := b.raw[directive]
+			if err := validateCSPDirective(directive, sources, raw); err != nil {
+				return nil, err
+			}
+		}
+
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(string(directive))
+
+		for _, source := range sources {
+			sb.WriteByte(' ')
+			if source == CSPNonceSource {
+				parts = append(parts, sb.String())
+				sb.Reset()
+				continue
+			}
+			sb.WriteString(string(source))
+		}
+
+		if directive == CSPReportTo && len(sources) > 0 {
+			reportTo = string(sources[0])
+		}
+	}
+	parts = append(parts, sb.String())
+
+	return &CSPPolicy{parts: parts, reportTo: reportTo, builder: b.Clone()}, nil
+}
+
+// MustBuild same as Build but it panics on validation errors.
+func (b *CSPBuilder) MustBuild() *CSPPolicy {
+	p, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+func dedupCSPSources(sources []CSPSource) []CSPSource {
+	seen := make(map[CSPSource]struct{}, len(sources))
+	result := make([]CSPSource, 0, len(sources))
+	for _, source := range sources {
+		if _, ok := seen[source]; ok {
+			continue
+		}
+		seen[source] = struct{}{}
+		result = append(result, source)
+	}
+
+	return result
+}
+
+func validateCSPDirective(directive CSPDirective, sources []CSPSource, raw bool) error {
+	kind, ok := cspDirectiveKinds[directive]
+	if !ok {
+		if !raw {
+			return fmt.Errorf("csp: unknown directive %q, use AddRaw to add it anyway", directive)
+		}
+
+		if !isCSPDirectiveName(string(directive)) {
+			return fmt.Errorf("csp: invalid directive name %q", directive)
+		}
+
+		kind = cspTokenList
+	}
+
+	switch kind {
+	case cspNoValue:
+		if len(sources) > 0 {
+			return fmt.Errorf("csp: directive %q does not accept values", directive)
+		}
+		return nil
+	case cspSingleToken:
+		if len(sources) != 1 {
+			return fmt.Errorf("csp: directive %q requires exactly one value", directive)
+		}
+	case cspSourceList:
+		if len(sources) == 0 {
+			return fmt.Errorf("csp: directive %q requires at least one source, use CSPNone to block everything", directive)
+		}
+	}
+
+	for _, source := range sources {
+		if source == "" || strings.ContainsAny(string(source), " \t\r\n;,") {
+			return fmt.Errorf("csp: directive %q: invalid value %q", directive, source)
+		}
+
+		if kind != cspSourceList {
+			if source == CSPNonceSource {
+				return fmt.Errorf("csp: directive %q does not accept a nonce", directive)
+			}
+			continue
+		}
+
+		if err := validateCSPSource(source); err != nil {
+			return fmt.Errorf("csp: directive %q: %w", directive, err)
+		}
+
+		if source == CSPNone && len(sources) > 1 {
+			return fmt.Errorf("csp: directive %q: 'none' cannot be combined with other sources", directive)
+		}
+	}
+
+	return nil
+}
+
+// isCSPDirectiveName reports whether "name" is a valid directive name,
+// i.e. lowercase letters, digits and dashes.
+func isCSPDirectiveName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateCSPSource(source CSPSource) error {
+	if source == CSPNonceSource {
+		return nil
+	}
+
+	s := string(source)
+	if !strings.HasPrefix(s, "'") {
+		if strings.Contains(s, "'") {
+			return fmt.Errorf("invalid source %q", s)
+		}
+		return nil // host or scheme source.
+	}
+
+	if _, ok := cspKeywords[source]; ok {
+		return nil
+	}
+
+	if len(s) < 2 || !strings.HasSuffix(s, "'") {
+		return fmt.Errorf("unterminated source %q", s)
+	}
+
+	inner := s[1 : len(s)-1]
+	if strings.HasPrefix(inner, "nonce-") {
+		return fmt.Errorf("static nonce source %q, use CSPNonceSource instead", s)
+	}
+
+	algorithm, digest, ok := strings.Cut(inner, "-")
+	if !ok {
+		return fmt.Errorf("unknown keyword %q", s)
+	}
+
+	size, ok := cspHashSizes[algorithm]
+	if !ok {
+		return fmt.Errorf("unknown keyword or hash algorithm %q", s)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil || len(b) != size {
+		return fmt.Errorf("invalid %s digest %q", algorithm, s)
+	}
+
+	return nil
+}
+
+// CSPPolicy is a validated and pre-rendered Content-Security-Policy.
+// Create one through NewCSPBuilder or ParseCSP
+// and set it to the Options.CSP or Options.CSPReportOnly fields.
+type CSPPolicy struct {
+	// parts of the rendered policy, a nonce source
+	// goes between each one of them.
+	parts    []string
+	reportTo string
+	// builder is a copy of the builder the policy was created from,
+	// or the parsed directives of a raw policy.
+	builder *CSPBuilder
+}
+
+// ParseCSP returns a CSPPolicy from a raw policy string,
+// the `$NONCE` placeholders are replaced by a per-request nonce source.
+// Unlike CSPBuilder, no validation takes place.
+// Directives which the builder does not know are kept as if they were added through AddRaw.
+func ParseCSP(policy string) *CSPPolicy {
+	b := NewCSPBuilder()
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+
+		sources := make([]CSPSource, 0, len(fields)-1)
+		for _, field := range fields[1:] {
+			sources = append(sources, CSPSource(field))
+		}
+		b.AddRaw(CSPDirective(strings.ToLower(fields[0])), sources...)
+	}
+
+	return &CSPPolicy{parts: strings.Split(policy, string(CSPNonceSource)), builder: b}
+}
+
+// Builder returns a new CSPBuilder initialized with the policy's directives,
+// so a different policy can be derived from this one.
+// The directives of policies created by ParseCSP
+// are validated when the returned builder is built.
+func (p *CSPPolicy) Builder() *CSPBuilder {
+	return p.builder.Clone()
+}
+
+// HasNonce reports whether the policy contains a nonce source.
+func (p *CSPPolicy) HasNonce() bool {
+	return len(p.parts) > 1
+}
+
+// ReportTo returns the "report-to" group of the policy, if any.
+func (p *CSPPolicy) ReportTo() string {
+	return p.reportTo
+}
+
+// String returns the policy with the nonce placeholders.
+func (p *CSPPolicy) String() string {
+	return strings.Join(p.parts, string(CSPNonceSource))
+}
+
+// Render returns the header value of the policy
+// with all nonce sources set to the given "nonce".
+func (p *CSPPolicy) Render(nonce string) string {
+	if len(p.parts) == 1 {
+		return p.parts[0]
+	}
+
+	const prefix, suffix = "'nonce-", "'"
+	n := (len(p.parts) - 1) * (len(prefix) + len(nonce) + len(suffix))
+	for _, part := range p.parts {
+		n += len(part)
+	}
+
+	var sb strings.Builder
+	sb.Grow(n)
+	for i, part := range p.parts {
+		if i > 0 {
+			sb.WriteString(prefix)
+			sb.WriteString(nonce)
+			sb.WriteString(suffix)
+		}
+		sb.WriteString(part)
+	}
+
+	return sb.String()
+}
+
+// cspFallbacks maps directives to the ones browsers use, in order, when they are missing.
+var cspFallbacks = map[CSPDirective][]CSPDirective{
+	CSPScriptSrcElem:  {CSPScriptSrc, CSPDefaultSrc},
+	CSPScriptSrcAttr:  {CSPScriptSrc, CSPDefaultSrc},
+	CSPStyleSrcElem:   {CSPStyleSrc, CSPDefaultSrc},
+	CSPStyleSrcAttr:   {CSPStyleSrc, CSPDefaultSrc},
+	CSPScriptSrc:      {CSPDefaultSrc},
+	CSPStyleSrc:       {CSPDefaultSrc},
+	CSPImgSrc:         {CSPDefaultSrc},
+	CSPFontSrc:        {CSPDefaultSrc},
+	CSPConnectSrc:     {CSPDefaultSrc},
+	CSPMediaSrc:       {CSPDefaultSrc},
+	CSPObjectSrc:      {CSPDefaultSrc},
+	CSPFrameSrc:       {CSPChildSrc, CSPDefaultSrc},
+	CSPFencedFrameSrc: {CSPFrameSrc, CSPChildSrc, CSPDefaultSrc},
+	CSPChildSrc:       {CSPDefaultSrc},
+	CSPWorkerSrc:      {CSPChildSrc, CSPScriptSrc, CSPDefaultSrc},
+	CSPManifestSrc:    {CSPDefaultSrc},
+}
+
+// effectiveDirective returns the directive of the policy which governs the given one, if any.
+func (p *CSPPolicy) effectiveDirective(directive CSPDirective) (CSPDirective, bool) {
+	if _, ok := p.builder.directives[directive]; ok {
+		return directive, true
+	}
+
+	for _, fallback := range cspFallbacks[directive] {
+		if _, ok := p.builder.directives[fallback]; ok {
+			return fallback, true
+		}
+	}
+
+	return "", false
+}
+
+// renderWith same as Render but it appends the "extra" sources to their effective directives,
+// extra sources of directives which are missing or set to 'none' are ignored.
+func (p *CSPPolicy) renderWith(nonce string, extra map[CSPDirective][]CSPSource) string {
+	if len(extra) == 0 {
+		return p.Render(nonce)
+	}
+
+	// sorted, so the sources of directives with the same effective directive are rendered in a stable order.
+	directives := make([]CSPDirective, 0, len(extra))
+	for directive := range extra {
+		directives = append(directives, directive)
+	}
+	sort.Slice(directives, func(i, j int) bool { return directives[i] < directives[j] })
+
+	additions := make(map[CSPDirective][]CSPSource, len(extra))
+	for _, directive := range directives {
+		if target, ok := p.effectiveDirective(directive); ok {
+			additions[target] = append(additions[target], extra[directive]...)
+		}
+	}
+
+	var sb strings.Builder
+	for i, directive := range p.builder.order {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(string(directive))
+
+		sources := p.builder.directives[directive]
+		if added := additions[directive]; len(added) > 0 && !(len(sources) == 1 && sources[0] == CSPNone) {
+			sources = append(append([]CSPSource(nil), sources...), added...)
+		}
+
+		for _, source := range dedupCSPSources(sources) {
+			sb.WriteByte(' ')
+			if source == CSPNonceSource {
+				sb.WriteString("'nonce-")
+				sb.WriteString(nonce)
+				sb.WriteByte('\'')
+				continue
+			}
+			sb.WriteString(string(source))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,154 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestCSPBuilder(t *testing.T) {
+	policy, err := NewCSPBuilder().
+		Add(CSPDefaultSrc, CSPSelf).
+		Add(CSPScriptSrc, CSPStrictDynamic, CSPNonceSource).
+		Add(CSPScriptSrc, CSPHash(CSPSHA256, []byte("alert(1)")), CSPStrictDynamic).
+		Add(CSPObjectSrc, CSPNone).
+		UpgradeInsecureRequests().
+		ReportTo("csp-endpoint").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, policy.HasNonce(), true)
+	expect(t, policy.ReportTo(), "csp-endpoint")
+	expect(t, policy.String(), "default-src 'self'; script-src 'strict-dynamic' $NONCE 'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='; object-src 'none'; upgrade-insecure-requests; report-to csp-endpoint")
+	expect(t, policy.Render("abc"), "default-src 'self'; script-src 'strict-dynamic' 'nonce-abc' 'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='; object-src 'none'; upgrade-insecure-requests; report-to csp-endpoint")
+}
+
+func TestCSPBuilderValidation(t *testing.T) {
+	cases := []struct {
+		name    string
+		builder *CSPBuilder
+	}{
+		{"empty", NewCSPBuilder()},
+		{"unknown directive", NewCSPBuilder().Add("scripts-src", CSPSelf)},
+		{"empty source list", NewCSPBuilder().Add(CSPScriptSrc)},
+		{"none combined", NewCSPBuilder().Add(CSPObjectSrc, CSPNone, CSPSelf)},
+		{"unknown keyword", NewCSPBuilder().Add(CSPScriptSrc, "'unsafe-everything'")},
+		{"unquoted keyword", NewCSPBuilder().Add(CSPScriptSrc, "self'")},
+		{"static nonce", NewCSPBuilder().Add(CSPScriptSrc, "'nonce-abc'")},
+		{"bad hash", NewCSPBuilder().Add(CSPScriptSrc, "'sha256-abc'")},
+		{"bad hash algorithm", NewCSPBuilder().Add(CSPScriptSrc, CSPHash("md5", nil))},
+		{"injection", NewCSPBuilder().Add(CSPImgSrc, "https://a.com; script-src *")},
+		{"no value directive", NewCSPBuilder().Add(CSPUpgradeInsecureRequests, CSPSelf)},
+		{"report-to with nonce", NewCSPBuilder().Add(CSPReportTo, CSPNonceSource)},
+	}
+
+	for _, c := range cases {
+		if _, err := c.builder.Build(); err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
+
+func TestCSPBuilderOptions(t *testing.T) {
+	policy := NewCSPBuilder().Add(CSPScriptSrc, CSPNonceSource, CSPSelf).MustBuild()
+	reportOnly := NewCSPBuilder().Add(CSPDefaultSrc, CSPSelf).MustBuild()
+
+	s := New(Options{
+		ContentSecurityPolicy: "default-src *", // overridden by CSP.
+		CSP:                   policy,
+		CSPReportOnly:         reportOnly,
+	})
+	app := iris.New()
+	app.Use(s.Handler)
+	app.Get("/foo", cspHandler)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	app.ServeHTTP(res, req)
+	expect(t, res.Code, http.StatusOK)
+
+	nonce := res.Body.String()
+	if nonce == "" || strings.Contains(nonce, "%") {
+		t.Fatalf("unexpected nonce: %q", nonce)
+	}
+	expect(t, res.Header().Get("Content-Security-Policy"), "script-src 'nonce-"+nonce+"' 'self'")
+	expect(t, res.Header().Get("Content-Security-Policy-Report-Only"), "default-src 'self'")
+}
+
+func TestCSPPercentLiteral(t *testing.T) {
+	// The raw policy is no longer passed through fmt.Sprintf.
+	s := New(Options{ContentSecurityPolicy: "script-src $NONCE https://a.com/%20x"})
+	app := iris.New()
+	app.Use(s.Handler)
+	app.Get("/foo", cspHandler)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	app.ServeHTTP(res, req)
+
+	expect(t, res.Header().Get("Content-Security-Policy"), "script-src 'nonce-"+res.Body.String()+"' https://a.com/%20x")
+}
+
+func TestCSPBuilderRaw(t *testing.T) {
+	policy, err := NewCSPBuilder().
+		Add(CSPDefaultSrc, CSPSelf).
+		Add(CSPBlockAllMixedContent).
+		AddRaw("fenced-frame-src", "https://ads.example.com").
+		AddRaw("x-experimental").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, policy.String(), "default-src 'self'; block-all-mixed-content; fenced-frame-src https://ads.example.com; x-experimental")
+
+	for name, b := range map[string]*CSPBuilder{
+		"invalid name":  NewCSPBuilder().AddRaw("x_Experimental"),
+		"injection":     NewCSPBuilder().AddRaw("x-experimental", "a; script-src *"),
+		"known invalid": NewCSPBuilder().AddRaw(CSPScriptSrc, "'unsafe-everything'"),
+	} {
+		if _, err = b.Build(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// parsed policies keep the directives the builder does not know.
+	parsed := ParseCSP("default-src 'self'; x-experimental a b; block-all-mixed-content")
+	derived, err := parsed.Builder().Set(CSPFrameAncestors, "https://partner.example.com").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, derived.String(), "default-src 'self'; x-experimental a b; block-all-mixed-content; frame-ancestors https://partner.example.com")
+}
+
+func TestCSPFallbacks(t *testing.T) {
+	policy := NewCSPBuilder().
+		Add(CSPDefaultSrc, CSPSelf).
+		Add(CSPScriptSrc, CSPSelf).
+		Add(CSPChildSrc, CSPSelf).
+		MustBuild()
+
+	for directive, expected := range map[CSPDirective]CSPDirective{
+		CSPWorkerSrc:      CSPChildSrc,
+		CSPFencedFrameSrc: CSPChildSrc,
+		CSPScriptSrcElem:  CSPScriptSrc,
+		CSPImgSrc:         CSPDefaultSrc,
+	} {
+		got, _ := policy.effectiveDirective(directive)
+		expect(t, got, expected)
+	}
+
+	policy = NewCSPBuilder().Add(CSPDefaultSrc, CSPSelf).Add(CSPScriptSrc, CSPSelf).MustBuild()
+	got, _ := policy.effectiveDirective(CSPWorkerSrc)
+	expect(t, got, CSPScriptSrc)
+}
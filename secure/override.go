@@ -0,0 +1,288 @@
+package secure
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+)
+
+// OverrideFunc modifies a copy of the base Options for a specific route or party.
+// See Secure.OverrideRoute, Secure.OverridePath and Secure.OverrideParty.
+type OverrideFunc func(o *Options)
+
+// MergeOptions returns a copy of the "base" Options with the
+// "overrides" applied in the given order, the base is never modified.
+// This is how the per-route options of a Secure middleware are resolved.
+func MergeOptions(base Options, overrides ...OverrideFunc) Options {
+	o := base
+	if base.AllowedHosts != nil {
+		o.AllowedHosts = append([]string(nil), base.AllowedHosts...)
+	}
+
+	if base.TrustedProxies != nil {
+		o.TrustedProxies = append([]string(nil), base.TrustedProxies...)
+	}
+
+	if base.RemoveResponseHeaders != nil {
+		o.RemoveResponseHeaders = append([]string(nil), base.RemoveResponseHeaders...)
+	}
+
+	if base.RewriteResponseHeaders != nil {
+		o.RewriteResponseHeaders = make(map[string]string, len(base.RewriteResponseHeaders))
+		for name, value := range base.RewriteResponseHeaders {
+			o.RewriteResponseHeaders[name] = value
+		}
+	}
+
+	if base.ReportingEndpoints != nil {
+		o.ReportingEndpoints = make(map[string]string, len(base.ReportingEndpoints))
+		for name, url := range base.ReportingEndpoints {
+			o.ReportingEndpoints[name] = url
+		}
+	}
+
+	if base.PermissionsPolicy != nil {
+		o.PermissionsPolicy = make(PermissionsPolicy, len(base.PermissionsPolicy))
+		for feature, allowlist := range base.PermissionsPolicy {
+			o.PermissionsPolicy[feature] = append([]string(nil), allowlist...)
+		}
+	}
+
+	for _, override := range overrides {
+		override(&o)
+	}
+
+	return o
+}
+
+// WithOptions returns an OverrideFunc which replaces all the options with "opts".
+func WithOptions(opts Options) OverrideFunc {
+	return func(o *Options) {
+		*o = opts
+	}
+}
+
+// WithCSP returns an OverrideFunc which replaces the Content-Security-Policy.
+// A nil "policy" removes the header.
+func WithCSP(policy *CSPPolicy) OverrideFunc {
+	return func(o *Options) {
+		o.CSP = policy
+		o.ContentSecurityPolicy = ""
+	}
+}
+
+// WithCSPReportOnly returns an OverrideFunc which replaces the Content-Security-Policy-Report-Only.
+// A nil "policy" removes the header.
+func WithCSPReportOnly(policy *CSPPolicy) OverrideFunc {
+	return func(o *Options) {
+		o.CSPReportOnly = policy
+		o.ContentSecurityPolicyReportOnly = ""
+	}
+}
+
+// WithFrameAncestors returns an OverrideFunc which allows the response to be embedded
+// by the given "sources", e.g. "https://partner.example.com".
+// It removes the X-Frame-Options header, as it cannot express a list of origins,
+// and sets the "frame-ancestors" directive of the enforced Content-Security-Policy instead.
+//
+// The sources are validated here, at registration, and it panics on invalid ones.
+// The rest of the policy is kept as it is, including the directives of a raw
+// ContentSecurityPolicy which are not validated, see ParseCSP.
+func WithFrameAncestors(sources ...CSPSource) OverrideFunc {
+	if err := validateCSPDirective(CSPFrameAncestors, dedupCSPSources(sources), false); err != nil {
+		panic(err)
+	}
+
+	return func(o *Options) {
+		o.FrameDeny = false
+		o.CustomFrameOptionsValue = ""
+
+		policy := o.CSP
+		if policy == nil && len(o.ContentSecurityPolicy) > 0 {
+			policy = ParseCSP(o.ContentSecurityPolicy)
+		}
+
+		var b *CSPBuilder
+		if policy != nil {
+			b = policy.Builder()
+		} else {
+			b = NewCSPBuilder()
+		}
+
+		// The new sources are already validated and the rest of the directives
+		// come from a built or a raw policy, so they are not validated again.
+		o.CSP, _ = b.Set(CSPFrameAncestors, sources...).build(false)
+		o.ContentSecurityPolicy = ""
+	}
+}
+
+type override struct {
+	// routeName or pathPrefix, one of them is set.
+	routeName  string
+	pathPrefix string
+	funcs      []OverrideFunc
+}
+
+func (ov override) matchPath(path string) bool {
+	if !strings.HasPrefix(path, ov.pathPrefix) {
+		return false
+	}
+
+	rest := path[len(ov.pathPrefix):]
+	return rest == "" || rest[0] == '/' || strings.HasSuffix(ov.pathPrefix, "/")
+}
+
+// OverrideRoute registers options overrides for the route with the given name (iris.Route.Name),
+// e.g. "GET/widget" or a custom name set through `app.Get(...).Name = "widget"`.
+//
+// Overrides are matched at request time, so the Secure middleware should be registered
+// through Use or UseGlobal, not UseRouter which runs before the route is known.
+// Path overrides are applied first, from the shortest to the longest prefix,
+// and route overrides last, all on top of the base Options (see MergeOptions).
+//
+// The Secure instances of the overrides are built here, at registration,
+// and it panics on invalid options, like New does.
+func (s *Secure) OverrideRoute(routeName string, overrides ...OverrideFunc) *Secure {
+	s.addOverride(override{routeName: routeName, funcs: overrides})
+	return s
+}
+
+// OverridePath registers options overrides for the routes under the "pathPrefix",
+// e.g. "/embed" matches "/embed" and "/embed/{id}" but not "/embedded".
+// The prefix is tested against the route's registered path template when
+// the route is known, otherwise against the request path.
+//
+// Read OverrideRoute for the merge order.
+func (s *Secure) OverridePath(pathPrefix string, overrides ...OverrideFunc) *Secure {
+	s.addOverride(override{pathPrefix: pathPrefix, funcs: overrides})
+	return s
+}
+
+// OverrideParty same as OverridePath but it accepts an Iris Party,
+// all of its routes get the "overrides".
+func (s *Secure) OverrideParty(p iris.Party, overrides ...OverrideFunc) *Secure {
+	return s.OverridePath(p.GetRelPath(), overrides...)
+}
+
+func (s *Secure) addOverride(ov override) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	overrides := append(append([]override(nil), s.overrides...), ov)
+	// Keep the path overrides sorted by prefix length, so merging order is well defined.
+	sort.SliceStable(overrides, func(i, j int) bool {
+		a, b := overrides[i], overrides[j]
+		if (a.routeName == "") != (b.routeName == "") {
+			return a.routeName == ""
+		}
+		return len(a.pathPrefix) < len(b.pathPrefix)
+	})
+
+	// On panic the current overrides are kept.
+	s.derived = s.buildDerived(overrides, s.badHostHandler)
+	s.overrides = overrides
+}
+
+// buildDerived returns the Secure instances of each set of "overrides" which a request can match,
+// keyed by their indices, see matchOverrides.
+func (s *Secure) buildDerived(overrides []override, badHostHandler iris.Handler) map[string]*Secure {
+	// The path overrides which match a request are the ones which match its longest matching prefix
+	// and the route overrides are the ones of its route name.
+	var pathSets, routeSets [][]int
+	pathSets = append(pathSets, nil)
+	routeSets = append(routeSets, nil)
+	seenRoutes := make(map[string]struct{})
+	for _, ov := range overrides {
+		if ov.routeName == "" {
+			pathSets = append(pathSets, matchOverrides(overrides, "", ov.pathPrefix, false))
+			continue
+		}
+
+		if _, ok := seenRoutes[ov.routeName]; !ok {
+			seenRoutes[ov.routeName] = struct{}{}
+			routeSets = append(routeSets, matchOverrides(overrides, ov.routeName, "", true))
+		}
+	}
+
+	derived := make(map[string]*Secure)
+	for _, pathSet := range pathSets {
+		for _, routeSet := range routeSets {
+			// path overrides are sorted before the route ones.
+			indices := append(append([]int(nil), pathSet...), routeSet...)
+			if len(indices) == 0 {
+				continue
+			}
+
+			k := overridesKey(indices)
+			if _, ok := derived[k]; ok {
+				continue
+			}
+
+			var funcs []OverrideFunc
+			for _, i := range indices {
+				funcs = append(funcs, overrides[i].funcs...)
+			}
+
+			d := New(MergeOptions(s.opt, funcs...))
+			d.badHostHandler = badHostHandler
+			derived[k] = d
+		}
+	}
+
+	return derived
+}
+
+// matchOverrides returns the indices of the "overrides" which match the "routeName" and the "path".
+// If "routeOnly" is true, the path overrides are skipped.
+func matchOverrides(overrides []override, routeName, path string, routeOnly bool) []int {
+	var indices []int
+	for i, ov := range overrides {
+		if ov.routeName != "" {
+			if ov.routeName != routeName {
+				continue
+			}
+		} else if routeOnly || !ov.matchPath(path) {
+			continue
+		}
+
+		indices = append(indices, i)
+	}
+
+	return indices
+}
+
+func overridesKey(indices []int) string {
+	var key strings.Builder
+	for _, i := range indices {
+		key.WriteString(strconv.Itoa(i))
+		key.WriteByte(',')
+	}
+
+	return key.String()
+}
+
+// resolve returns the Secure instance which serves the current request,
+// it's "s" itself when no overrides match.
+func (s *Secure) resolve(ctx iris.Context) *Secure {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.overrides) == 0 {
+		return s
+	}
+
+	var routeName, path string
+	if route := ctx.GetCurrentRoute(); route != nil {
+		routeName, path = route.Name(), route.Path()
+	} else {
+		path = ctx.Path()
+	}
+
+	if d, ok := s.derived[overridesKey(matchOverrides(s.overrides, routeName, path, false))]; ok {
+		return d
+	}
+
+	return s
+}
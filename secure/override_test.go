@@ -0,0 +1,135 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestMergeOptions(t *testing.T) {
+	base := Options{
+		FrameDeny:          true,
+		AllowedHosts:       []string{"example.com"},
+		ReportingEndpoints: map[string]string{"default": "https://example.com/r"},
+	}
+
+	merged := MergeOptions(base,
+		func(o *Options) { o.FrameDeny = false },
+		func(o *Options) {
+			o.AllowedHosts[0] = "other.com"
+			o.ReportingEndpoints["default"] = "https://other.com/r"
+		},
+		func(o *Options) { o.ContentTypeNosniff = true },
+	)
+
+	expect(t, merged.FrameDeny, false)
+	expect(t, merged.ContentTypeNosniff, true)
+	expect(t, merged.AllowedHosts[0], "other.com")
+	// Base is untouched.
+	expect(t, base.FrameDeny, true)
+	expect(t, base.AllowedHosts[0], "example.com")
+	expect(t, base.ReportingEndpoints["default"], "https://example.com/r")
+}
+
+func TestOverrides(t *testing.T) {
+	s := New(Options{
+		FrameDeny:             true,
+		ContentTypeNosniff:    true,
+		ContentSecurityPolicy: "default-src 'self'; script-src $NONCE",
+	})
+
+	app := iris.New()
+	app.Use(s.Handler)
+	app.Get("/", cspHandler)
+	embed := app.Party("/embed")
+	embed.Get("/{id}", cspHandler)
+	embed.Get("/raw", cspHandler).Name = "raw"
+	app.Get("/embedded", cspHandler)
+
+	s.OverrideParty(embed, WithFrameAncestors("https://partner.example.com"))
+	s.OverrideRoute("raw", WithCSP(nil), func(o *Options) { o.ContentTypeNosniff = false })
+
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		app.ServeHTTP(res, req)
+		expect(t, res.Code, http.StatusOK)
+		return res
+	}
+
+	for _, path := range []string{"/", "/embedded"} {
+		res := get(path)
+		expect(t, res.Header().Get("X-Frame-Options"), "DENY")
+		expect(t, res.Header().Get("Content-Security-Policy"), "default-src 'self'; script-src 'nonce-"+res.Body.String()+"'")
+	}
+
+	res := get("/embed/1")
+	expect(t, res.Header().Get("X-Frame-Options"), "")
+	expect(t, res.Header().Get("X-Content-Type-Options"), "nosniff")
+	expect(t, res.Header().Get("Content-Security-Policy"),
+		"default-src 'self'; script-src 'nonce-"+res.Body.String()+"'; frame-ancestors https://partner.example.com")
+
+	res = get("/embed/raw")
+	expect(t, res.Header().Get("X-Frame-Options"), "")
+	expect(t, res.Header().Get("X-Content-Type-Options"), "")
+	expect(t, res.Header().Get("Content-Security-Policy"), "")
+}
+
+func TestFrameAncestorsRawPolicy(t *testing.T) {
+	s := New(Options{ContentSecurityPolicy: "default-src 'self'; block-all-mixed-content; x-experimental a"})
+	s.OverridePath("/embed", WithFrameAncestors("https://partner.example.com"))
+
+	app := iris.New()
+	app.Use(s.Handler)
+	app.Get("/embed", cspHandler)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/embed", nil)
+	app.ServeHTTP(res, req)
+	expect(t, res.Code, http.StatusOK)
+	expect(t, res.Header().Get("Content-Security-Policy"),
+		"default-src 'self'; block-all-mixed-content; x-experimental a; frame-ancestors https://partner.example.com")
+
+	// invalid sources are reported at registration, not on the first request.
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	WithFrameAncestors("https://a.com; script-src *")
+}
+
+func TestOverridesRegistration(t *testing.T) {
+	s := New(Options{FrameDeny: true})
+	s.OverridePath("/embed", func(o *Options) { o.FrameDeny = false })
+	s.OverridePath("/embed/admin", func(o *Options) { o.ContentTypeNosniff = true })
+	s.OverrideRoute("widget", func(o *Options) { o.BrowserXSSFilter = true })
+
+	// {}, {/embed}, {/embed, /embed/admin} by {}, {widget}, without the empty set.
+	expect(t, len(s.derived), 5)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic at registration")
+			}
+		}()
+		s.OverridePath("/hosts", func(o *Options) {
+			o.AllowedHosts = []string{"("}
+			o.AllowedHostsAreRegex = true
+		})
+	}()
+
+	// the invalid override is not registered.
+	expect(t, len(s.overrides), 3)
+	expect(t, len(s.derived), 5)
+}
@@ -0,0 +1,100 @@
+package secure
+
+import "slices"
+
+// Presets return a new Options value on each call,
+// so the result can be customized before passed to New, e.g.
+//
+//	opts := secure.StrictPreset()
+//	opts.AllowedHosts = []string{"example.com"}
+//	s, err := secure.NewValidated(opts)
+
+// StrictPreset returns the Options for HTML applications served over HTTPS only.
+// It redirects to HTTPS, sends a two years HSTS (without preload, which is an explicit commitment),
+// denies framing, enables a nonce-based strict CSP (see CSPNonce), isolates the browsing context
+// disables powerful browser features, removes the IdentifyingHeaders and
+// prevents caching of responses which set cookies.
+func StrictPreset() Options {
+	return Options{
+		SSLRedirect:          true,
+		STSSeconds:           63072000,
+		STSIncludeSubdomains: true,
+		FrameDeny:            true,
+		ContentTypeNosniff:   true,
+		ReferrerPolicy:       "no-referrer",
+		CSP: NewCSPBuilder().
+			Add(CSPDefaultSrc, CSPSelf).
+			Add(CSPScriptSrc, CSPNonceSource, CSPStrictDynamic).
+			Add(CSPStyleSrc, CSPSelf, CSPNonceSource).
+			Add(CSPObjectSrc, CSPNone).
+			Add(CSPBaseURI, CSPNone).
+			Add(CSPFormAction, CSPSelf).
+			Add(CSPFrameAncestors, CSPNone).
+			UpgradeInsecureRequests().
+			MustBuild(),
+		PermissionsPolicy:            strictPermissionsPolicy(),
+		CrossOriginOpenerPolicy:      COOPSameOrigin,
+		CrossOriginResourcePolicy:    CORPSameOrigin,
+		PermittedCrossDomainPolicies: CrossDomainNone,
+		RemoveResponseHeaders:        slices.Clone(IdentifyingHeaders),
+		NoStoreOnSetCookie:           true,
+	}
+}
+
+// APIPreset returns the Options for JSON APIs.
+// It does not redirect to HTTPS, as redirects drop request bodies and hide client mistakes,
+// but it sends HSTS and a CSP which blocks everything, as responses are never rendered as documents.
+func APIPreset() Options {
+	return Options{
+		STSSeconds:           63072000,
+		STSIncludeSubdomains: true,
+		FrameDeny:            true,
+		ContentTypeNosniff:   true,
+		ReferrerPolicy:       "no-referrer",
+		CSP: NewCSPBuilder().
+			Add(CSPDefaultSrc, CSPNone).
+			Add(CSPFrameAncestors, CSPNone).
+			MustBuild(),
+		CrossOriginOpenerPolicy:      COOPSameOrigin,
+		CrossOriginResourcePolicy:    CORPSameOrigin,
+		PermittedCrossDomainPolicies: CrossDomainNone,
+		RemoveResponseHeaders:        slices.Clone(IdentifyingHeaders),
+		NoStoreOnSetCookie:           true,
+	}
+}
+
+// LegacyBrowserPreset returns the Options for HTML applications which must
+// support old browsers. On top of the StrictPreset headers, it keeps the obsolete
+// X-XSS-Protection and Feature-Policy headers and uses a backwards compatible CSP:
+// browsers without 'strict-dynamic' fall back to the https: and 'unsafe-inline' sources,
+// modern ones ignore them.
+func LegacyBrowserPreset() Options {
+	o := StrictPreset()
+	o.ReferrerPolicy = "strict-origin-when-cross-origin"
+	o.CSP = NewCSPBuilder().
+		Add(CSPDefaultSrc, CSPSelf).
+		Add(CSPScriptSrc, CSPNonceSource, CSPStrictDynamic, CSPUnsafeInline, "https:").
+		Add(CSPStyleSrc, CSPSelf, CSPUnsafeInline).
+		Add(CSPObjectSrc, CSPNone).
+		Add(CSPBaseURI, CSPNone).
+		Add(CSPFrameAncestors, CSPNone).
+		MustBuild()
+	// Legacy applications usually depend on popups, e.g. for third-party sign in.
+	o.CrossOriginOpenerPolicy = COOPSameOriginAllowPopups
+	// Headers for browsers which do not support CSP and Permissions-Policy,
+	// newer ones ignore them.
+	o.BrowserXSSFilter = true
+	o.FeaturePolicy = "camera 'none'; microphone 'none'; geolocation 'none'; payment 'none'; usb 'none'"
+
+	return o
+}
+
+func strictPermissionsPolicy() PermissionsPolicy {
+	return PermissionsPolicy{
+		"camera":      {},
+		"microphone":  {},
+		"geolocation": {},
+		"payment":     {},
+		"usb":         {},
+	}
+}
@@ -0,0 +1,195 @@
+package secure
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/context"
+)
+
+const (
+	forwardedHeader                = "Forwarded"
+	defaultForwardedProtoHeader    = "X-Forwarded-Proto"
+	defaultForwardedHostHeader     = "X-Forwarded-Host"
+	forwardedForHeader             = "X-Forwarded-For"
+	forwardedParamFor              = "for"
+	forwardedParamProto            = "proto"
+	forwardedParamHost             = "host"
+	forwardedProtoHTTPS            = "https"
+	forwardedUnknownOrObfuscatedIP = "unknown"
+)
+
+// parseTrustedProxies parses a list of IPs and CIDRs.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy IP %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// isTrustedProxy reports whether the "ip" belongs to one of the trusted proxies.
+func (s *Secure) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveSchemeAndHost reports whether the client connection is secure and the host it requested.
+// If no TrustedProxies are configured then the Context's IsSSL and Host methods are used,
+// otherwise the forwarded headers are taken into account only when the peer is a trusted proxy.
+func (s *Secure) resolveSchemeAndHost(ctx iris.Context) (ssl bool, host string) {
+	if len(s.trustedProxies) == 0 {
+		return ctx.IsSSL(), ctx.Host()
+	}
+
+	r := ctx.Request()
+	ssl = r.TLS != nil || strings.EqualFold(r.URL.Scheme, forwardedProtoHTTPS)
+	host = context.GetHost(r)
+
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	if !s.isTrustedProxy(peer) {
+		return
+	}
+
+	if values := r.Header.Values(forwardedHeader); len(values) > 0 {
+		if element := s.forwardedElement(values); element != nil {
+			if proto := element[forwardedParamProto]; proto != "" {
+				ssl = strings.EqualFold(proto, forwardedProtoHTTPS)
+			}
+
+			if h := element[forwardedParamHost]; h != "" {
+				host = h
+			}
+		}
+
+		return
+	}
+
+	if proto := s.forwardedValue(r.Header, s.forwardedProtoHeader); proto != "" {
+		ssl = strings.EqualFold(proto, forwardedProtoHTTPS)
+	}
+
+	if h := s.forwardedValue(r.Header, s.forwardedHostHeader); h != "" {
+		host = h
+	}
+
+	return
+}
+
+// forwardedElement returns the parameters of the RFC 7239 Forwarded element
+// appended by the outermost trusted proxy: elements are walked from right to left,
+// skipping the ones whose "for" is a trusted proxy itself.
+func (s *Secure) forwardedElement(values []string) map[string]string {
+	var elements []string
+	for _, value := range values {
+		elements = append(elements, strings.Split(value, ",")...)
+	}
+
+	var element map[string]string
+	for i := len(elements) - 1; i >= 0; i-- {
+		element = parseForwardedElement(elements[i])
+
+		forIP := element[forwardedParamFor]
+		if forIP == "" || forIP == forwardedUnknownOrObfuscatedIP || strings.HasPrefix(forIP, "_") {
+			break
+		}
+
+		if host, _, err := net.SplitHostPort(forIP); err == nil {
+			forIP = host
+		}
+
+		if !s.isTrustedProxy(forIP) {
+			break
+		}
+	}
+
+	return element
+}
+
+// parseForwardedElement parses a single `for=...;proto=...;host=...` element.
+func parseForwardedElement(element string) map[string]string {
+	params := make(map[string]string, 4)
+	for _, pair := range strings.Split(element, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return params
+}
+
+// forwardedValue returns the value of a comma separated X-Forwarded-* header
+// appended by the outermost trusted proxy: values are walked from right to left,
+// skipping one value per trusted proxy at the end of the X-Forwarded-For header,
+// so values sent by the client are never used.
+func (s *Secure) forwardedValue(header http.Header, name string) string {
+	values := headerValues(header, name)
+	if len(values) == 0 {
+		return ""
+	}
+
+	i := len(values) - 1
+	forwardedFor := headerValues(header, forwardedForHeader)
+	for j := len(forwardedFor) - 1; j >= 0 && i > 0; j-- {
+		if !s.isTrustedProxy(forwardedFor[j]) {
+			break
+		}
+		i--
+	}
+
+	return values[i]
+}
+
+// headerValues returns the comma separated values of all the "name" header lines.
+func headerValues(header http.Header, name string) []string {
+	var values []string
+	for _, line := range header.Values(name) {
+		for _, value := range strings.Split(line, ",") {
+			values = append(values, strings.TrimSpace(value))
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,104 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	s := New(Options{
+		SSLRedirect:    true,
+		STSSeconds:     300,
+		AllowedHosts:   []string{"www.example.com"},
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"},
+	})
+
+	app := iris.New()
+	app.Use(s.Handler)
+	app.Get("/foo", func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		host       string
+		headers    map[string]string
+		code       int
+		location   string
+		sts        bool
+	}{
+		{"plain http", "10.0.0.1:1234", "www.example.com", nil,
+			http.StatusMovedPermanently, "https://www.example.com/foo", false},
+		{"x-forwarded-proto", "10.0.0.1:1234", "www.example.com", map[string]string{"X-Forwarded-Proto": "https"},
+			http.StatusOK, "", true},
+		{"x-forwarded-proto untrusted", "8.8.8.8:1234", "www.example.com", map[string]string{"X-Forwarded-Proto": "https"},
+			http.StatusMovedPermanently, "https://www.example.com/foo", false},
+		{"x-forwarded-host", "192.168.1.1:1234", "internal:8080", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "www.example.com"},
+			http.StatusOK, "", true},
+		{"x-forwarded-host redirect", "192.168.1.1:1234", "internal:8080", map[string]string{"X-Forwarded-Host": "www.example.com"},
+			http.StatusMovedPermanently, "https://www.example.com/foo", false},
+		{"x-forwarded-host untrusted", "8.8.8.8:1234", "internal:8080", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "www.example.com"},
+			http.StatusInternalServerError, "", false},
+		{"forwarded", "10.0.0.1:1234", "internal", map[string]string{"Forwarded": `for=1.2.3.4;proto=https;host="www.example.com"`},
+			http.StatusOK, "", true},
+		{"forwarded spoofed by client", "10.0.0.1:1234", "internal", map[string]string{"Forwarded": `for=1.1.1.1;proto=https;host=www.example.com, for=1.2.3.4;proto=http;host=www.example.com`},
+			http.StatusMovedPermanently, "https://www.example.com/foo", false},
+		{"forwarded proxy chain", "10.0.0.1:1234", "internal", map[string]string{"Forwarded": `for=1.2.3.4;proto=https;host=www.example.com, for=10.0.0.2;proto=http;host=internal`},
+			http.StatusOK, "", true},
+		{"x-forwarded spoofed by client", "10.0.0.1:1234", "internal", map[string]string{"X-Forwarded-Proto": "http, https", "X-Forwarded-Host": "evil.com, www.example.com"},
+			http.StatusOK, "", true},
+		{"x-forwarded-proto spoofed by client", "10.0.0.1:1234", "www.example.com", map[string]string{"X-Forwarded-Proto": "https, http"},
+			http.StatusMovedPermanently, "https://www.example.com/foo", false},
+		{"x-forwarded proxy chain", "10.0.0.1:1234", "internal", map[string]string{"X-Forwarded-For": "1.2.3.4, 10.0.0.2", "X-Forwarded-Proto": "evil, https, http", "X-Forwarded-Host": "evil.com, www.example.com, internal"},
+			http.StatusOK, "", true},
+		{"forwarded over x-forwarded", "10.0.0.1:1234", "internal", map[string]string{"Forwarded": `for="[2001:db8::1]:80";proto=https;host=www.example.com`, "X-Forwarded-Proto": "http"},
+			http.StatusOK, "", true},
+	}
+
+	for _, c := range cases {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/foo", nil)
+		req.Host = c.host
+		req.RemoteAddr = c.remoteAddr
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		app.ServeHTTP(res, req)
+
+		if res.Code != c.code {
+			t.Errorf("%s: expected status %d but got %d", c.name, c.code, res.Code)
+			continue
+		}
+
+		expect(t, res.Header().Get("Location"), c.location)
+		expect(t, res.Header().Get("Strict-Transport-Security") != "", c.sts)
+	}
+
+	// multiple header lines, the client's one comes first.
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req.Host = "internal"
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Add("X-Forwarded-Host", "evil.com")
+	req.Header.Add("X-Forwarded-Host", "www.example.com")
+	app.ServeHTTP(res, req)
+	expect(t, res.Code, http.StatusMovedPermanently)
+	expect(t, res.Header().Get("Location"), "https://www.example.com/foo")
+}
+
+func TestTrustedProxiesInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+
+	New(Options{TrustedProxies: []string{"10.0.0.0/33"}})
+}
@@ -0,0 +1,409 @@
+package secure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/context"
+)
+
+const (
+	reportingEndpointsHeader = "Reporting-Endpoints"
+
+	cspReportContentType     = "application/csp-report"
+	reportsJSONContentType   = "application/reports+json"
+	defaultReportMaxBodySize = 64 << 10 // 64KB.
+	defaultReportMaxReports  = 100
+	defaultReportDedupWindow = time.Minute
+	defaultReportDedupSize   = 10000
+
+	// CSPViolationReportType is the Report.Type of Content-Security-Policy violations.
+	CSPViolationReportType = "csp-violation"
+)
+
+// Report is a single, normalized, report received by the ReportCollector.
+// Both the legacy `application/csp-report` and the
+// Reporting API `application/reports+json` payloads are converted to it.
+type Report struct {
+	// Type is the report type, e.g. "csp-violation", "deprecation" or "coep".
+	Type string `json:"type"`
+	// Age is the number of milliseconds between the report's timestamp and the time it was sent.
+	Age int64 `json:"age"`
+	// URL is the address of the document or worker the report was generated from.
+	URL string `json:"url"`
+	// UserAgent of the browser sent the report.
+	UserAgent string `json:"user_agent"`
+	// CSP holds the parsed violation when Type is CSPViolationReportType.
+	CSP *CSPViolation `json:"-"`
+	// Body is the raw report body.
+	Body json.RawMessage `json:"body"`
+}
+
+// CSPViolation is the body of a Content-Security-Policy violation report.
+type CSPViolation struct {
+	DocumentURL        string `json:"documentURL"`
+	Referrer           string `json:"referrer"`
+	BlockedURL         string `json:"blockedURL"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	OriginalPolicy     string `json:"originalPolicy"`
+	SourceFile         string `json:"sourceFile"`
+	Sample             string `json:"sample"`
+	Disposition        string `json:"disposition"`
+	StatusCode         int    `json:"statusCode"`
+	LineNumber         int    `json:"lineNumber"`
+	ColumnNumber       int    `json:"columnNumber"`
+}
+
+// legacyCSPViolation is the body of an `application/csp-report` request.
+type legacyCSPViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	Referrer           string `json:"referrer"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	OriginalPolicy     string `json:"original-policy"`
+	SourceFile         string `json:"source-file"`
+	ScriptSample       string `json:"script-sample"`
+	Disposition        string `json:"disposition"`
+	StatusCode         int    `json:"status-code"`
+	LineNumber         int    `json:"line-number"`
+	ColumnNumber       int    `json:"column-number"`
+}
+
+func (v legacyCSPViolation) normalize() *CSPViolation {
+	directive := v.EffectiveDirective
+	if directive == "" {
+		// Old browsers send the violated directive only,
+		// which may contain the directive's sources too.
+		directive, _, _ = strings.Cut(v.ViolatedDirective, " ")
+	}
+
+	return &CSPViolation{
+		DocumentURL:        v.DocumentURI,
+		Referrer:           v.Referrer,
+		BlockedURL:         v.BlockedURI,
+		EffectiveDirective: directive,
+		OriginalPolicy:     v.OriginalPolicy,
+		SourceFile:         v.SourceFile,
+		Sample:             v.ScriptSample,
+		Disposition:        v.Disposition,
+		StatusCode:         v.StatusCode,
+		LineNumber:         v.LineNumber,
+		ColumnNumber:       v.ColumnNumber,
+	}
+}
+
+// ReportSink receives the reports accepted by a ReportCollector.
+// Implementations must be safe for concurrent use.
+type ReportSink interface {
+	Report(ctx iris.Context, report Report)
+}
+
+// ReportSinkFunc is a function which implements the ReportSink interface.
+type ReportSinkFunc func(ctx iris.Context, report Report)
+
+// Report calls fn(ctx, report).
+func (fn ReportSinkFunc) Report(ctx iris.Context, report Report) {
+	fn(ctx, report)
+}
+
+// LoggerReportSink returns a ReportSink which logs the reports
+// through the Iris Application's logger as warnings.
+func LoggerReportSink() ReportSink {
+	return ReportSinkFunc(func(ctx iris.Context, report Report) {
+		if v := report.CSP; v != nil {
+			ctx.Application().Logger().Warnf("secure: %s report: %s blocked %q by %q (%s:%d:%d)",
+				report.Type, v.DocumentURL, v.BlockedURL, v.EffectiveDirective, v.SourceFile, v.LineNumber, v.ColumnNumber)
+			return
+		}
+
+		ctx.Application().Logger().Warnf("secure: %s report: %s: %s", report.Type, report.URL, report.Body)
+	})
+}
+
+// ChannelReportSink returns a ReportSink which sends the reports to the "ch" channel.
+// Reports are dropped when the channel is not ready to receive,
+// so the collector never blocks on a slow consumer.
+func ChannelReportSink(ch chan<- Report) ReportSink {
+	return ReportSinkFunc(func(_ iris.Context, report Report) {
+		select {
+		case ch <- report:
+		default:
+		}
+	})
+}
+
+// ReportCollectorOptions holds the configuration for the ReportCollector.
+type ReportCollectorOptions struct {
+	// Sink receives the accepted reports. Defaults to LoggerReportSink.
+	Sink ReportSink
+	// MaxBodySize is the maximum request body size in bytes. Default is 64KB.
+	MaxBodySize int64
+	// MaxReports is the maximum number of reports per request, the rest are ignored. Default is 100.
+	MaxReports int
+	// DedupWindow is the period in which identical reports are forwarded to the Sink once. Default is one minute.
+	// A negative value disables deduplication.
+	DedupWindow time.Duration
+	// DedupMaxEntries is the maximum number of reports remembered for deduplication. Default is 10000.
+	DedupMaxEntries int
+}
+
+// ReportCollector is an endpoint which accepts Content-Security-Policy and
+// Reporting API violation reports and forwards them to a ReportSink.
+type ReportCollector struct {
+	opts ReportCollectorOptions
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewReportCollector returns a new ReportCollector.
+// Register its Handler to the path that the "report-to" and "report-uri"
+// CSP directives and the Options.ReportingEndpoints point to.
+//
+// Example:
+//
+//	collector := secure.NewReportCollector(secure.ReportCollectorOptions{})
+//	app.Post("/csp-reports", collector.Handler)
+func NewReportCollector(opts ReportCollectorOptions) *ReportCollector {
+	if opts.Sink == nil {
+		opts.Sink = LoggerReportSink()
+	}
+
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultReportMaxBodySize
+	}
+
+	if opts.MaxReports <= 0 {
+		opts.MaxReports = defaultReportMaxReports
+	}
+
+	if opts.DedupWindow == 0 {
+		opts.DedupWindow = defaultReportDedupWindow
+	}
+
+	if opts.DedupMaxEntries <= 0 {
+		opts.DedupMaxEntries = defaultReportDedupSize
+	}
+
+	return &ReportCollector{
+		opts: opts,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Handler parses the request's reports and forwards them to the Sink.
+// It responds with 204 (No Content) on success, 415 on unsupported content types,
+// 413 on large payloads and 400 on malformed ones.
+func (c *ReportCollector) Handler(ctx iris.Context) {
+	if ctx.Method() != iris.MethodPost {
+		ctx.StopWithStatus(iris.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType := ctx.GetContentTypeRequested()
+	switch contentType {
+	case cspReportContentType, reportsJSONContentType, context.ContentJSONHeaderValue:
+	default:
+		ctx.StopWithStatus(iris.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request().Body, c.opts.MaxBodySize+1))
+	if err != nil {
+		ctx.StopWithStatus(iris.StatusBadRequest)
+		return
+	}
+
+	if int64(len(body)) > c.opts.MaxBodySize {
+		ctx.StopWithStatus(iris.StatusRequestEntityTooLarge)
+		return
+	}
+
+	reports, err := parseReports(body, ctx.GetHeader("User-Agent"), c.opts.MaxReports)
+	if err != nil {
+		ctx.StopWithText(iris.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
+	for _, report := range reports {
+		if c.isDuplicate(report, now) {
+			continue
+		}
+
+		c.opts.Sink.Report(ctx, report)
+	}
+
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+// isDuplicate reports whether an identical report was seen in the dedup window.
+func (c *ReportCollector) isDuplicate(report Report, now time.Time) bool {
+	if c.opts.DedupWindow < 0 {
+		return false
+	}
+
+	key := reportKey(report)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if at, ok := c.seen[key]; ok && now.Sub(at) < c.opts.DedupWindow {
+		return true
+	}
+
+	if len(c.seen) >= c.opts.DedupMaxEntries {
+		for k, at := range c.seen {
+			if now.Sub(at) >= c.opts.DedupWindow {
+				delete(c.seen, k)
+			}
+		}
+
+		if len(c.seen) >= c.opts.DedupMaxEntries {
+			// Still full of fresh entries, start over instead of growing unbounded.
+			c.seen = make(map[string]time.Time)
+		}
+	}
+
+	c.seen[key] = now
+	return false
+}
+
+func reportKey(report Report) string {
+	if v := report.CSP; v != nil {
+		return strings.Join([]string{report.Type, v.DocumentURL, v.BlockedURL, v.EffectiveDirective,
+			v.Disposition, v.SourceFile, strconv.Itoa(v.LineNumber), strconv.Itoa(v.ColumnNumber)}, "\x00")
+	}
+
+	return report.Type + "\x00" + report.URL + "\x00" + string(report.Body)
+}
+
+var errEmptyReport = errors.New("empty report")
+
+// parseReports parses a legacy CSP report object or a Reporting API array of reports.
+func parseReports(body []byte, userAgent string, maxReports int) ([]Report, error) {
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 {
+		return nil, errEmptyReport
+	}
+
+	if body[0] == '{' {
+		var legacy struct {
+			CSPReport *legacyCSPViolation `json:"csp-report"`
+		}
+		if err := json.Unmarshal(body, &legacy); err != nil {
+			return nil, fmt.Errorf("invalid csp report: %w", err)
+		}
+
+		if legacy.CSPReport == nil {
+			return nil, errEmptyReport
+		}
+
+		v := legacy.CSPReport.normalize()
+		if err := validateCSPViolation(v); err != nil {
+			return nil, err
+		}
+
+		return []Report{{
+			Type:      CSPViolationReportType,
+			URL:       v.DocumentURL,
+			UserAgent: userAgent,
+			CSP:       v,
+			Body:      json.RawMessage(body),
+		}}, nil
+	}
+
+	var reports []Report
+	if err := json.Unmarshal(body, &reports); err != nil {
+		return nil, fmt.Errorf("invalid reports: %w", err)
+	}
+
+	if len(reports) == 0 {
+		return nil, errEmptyReport
+	}
+
+	if len(reports) > maxReports {
+		reports = reports[:maxReports]
+	}
+
+	valid := reports[:0]
+	for _, report := range reports {
+		if report.Type == "" || !isValidReportURL(report.URL) {
+			continue
+		}
+
+		if report.UserAgent == "" {
+			report.UserAgent = userAgent
+		}
+
+		if report.Type == CSPViolationReportType {
+			v := new(CSPViolation)
+			if err := json.Unmarshal(report.Body, v); err != nil || validateCSPViolation(v) != nil {
+				continue
+			}
+			report.CSP = v
+		}
+
+		valid = append(valid, report)
+	}
+
+	if len(valid) == 0 {
+		return nil, errEmptyReport
+	}
+
+	return valid, nil
+}
+
+func validateCSPViolation(v *CSPViolation) error {
+	if !isValidReportURL(v.DocumentURL) {
+		return fmt.Errorf("invalid csp report: document url: %q", v.DocumentURL)
+	}
+
+	if v.EffectiveDirective == "" {
+		return errors.New("invalid csp report: missing directive")
+	}
+
+	return nil
+}
+
+func isValidReportURL(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ""
+}
+
+// renderReportingEndpoints returns the Reporting-Endpoints header value
+// of the given endpoint name-URL pairs, sorted by name.
+func renderReportingEndpoints(endpoints map[string]string) string {
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(endpoints[name])
+		sb.WriteByte('"')
+	}
+
+	return sb.String()
+}
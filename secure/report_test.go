@@ -0,0 +1,93 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestReportCollector(t *testing.T) {
+	ch := make(chan Report, 10)
+	collector := NewReportCollector(ReportCollectorOptions{
+		Sink:        ChannelReportSink(ch),
+		MaxBodySize: 1024,
+	})
+
+	app := iris.New()
+	app.Post("/reports", collector.Handler)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(contentType, body string) int {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/reports", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("User-Agent", "test")
+		app.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	legacy := `{"csp-report":{"document-uri":"https://example.com/","blocked-uri":"inline","violated-directive":"script-src 'self'","line-number":3}}`
+	expect(t, send("application/csp-report", legacy), http.StatusNoContent)
+	// Duplicate.
+	expect(t, send("application/csp-report", legacy), http.StatusNoContent)
+
+	reports := `[{"type":"csp-violation","age":10,"url":"https://example.com/","user_agent":"chrome",
+	"body":{"documentURL":"https://example.com/","blockedURL":"eval","effectiveDirective":"script-src","disposition":"enforce"}},
+	{"type":"deprecation","url":"https://example.com/a","body":{"id":"x"}},
+	{"type":"","url":"https://example.com/b","body":{}}]`
+	expect(t, send("application/reports+json", reports), http.StatusNoContent)
+
+	expect(t, send("text/plain", legacy), http.StatusUnsupportedMediaType)
+	expect(t, send("application/csp-report", `{"csp-report":{"document-uri":"/relative"}}`), http.StatusBadRequest)
+	expect(t, send("application/csp-report", `{`), http.StatusBadRequest)
+	expect(t, send("application/reports+json", "["+strings.Repeat(" ", 2048)+"]"), http.StatusRequestEntityTooLarge)
+
+	close(ch)
+	var got []Report
+	for r := range ch {
+		got = append(got, r)
+	}
+
+	expect(t, len(got), 3)
+	expect(t, got[0].Type, CSPViolationReportType)
+	expect(t, got[0].UserAgent, "test")
+	expect(t, got[0].CSP.EffectiveDirective, "script-src")
+	expect(t, got[0].CSP.LineNumber, 3)
+	expect(t, got[1].CSP.BlockedURL, "eval")
+	expect(t, got[1].UserAgent, "chrome")
+	expect(t, got[2].Type, "deprecation")
+	expect(t, got[2].CSP == nil, true)
+}
+
+func TestReportingEndpointsHeader(t *testing.T) {
+	s := New(Options{
+		ReportingEndpoints: map[string]string{
+			"default":      "https://example.com/reports",
+			"csp-endpoint": "https://example.com/csp",
+		},
+	})
+
+	header, err := s.ProcessNoModifyRequest(newTestContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, header.Get("Reporting-Endpoints"), `csp-endpoint="https://example.com/csp", default="https://example.com/reports"`)
+}
+
+func newTestContext(t *testing.T) iris.Context {
+	t.Helper()
+
+	app := iris.New()
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	return app.ContextPool.Acquire(httptest.NewRecorder(), req)
+}
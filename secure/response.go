@@ -0,0 +1,117 @@
+package secure
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	cacheControlHeader = "Cache-Control"
+	cacheControlValue  = "no-store"
+	setCookieHeader    = "Set-Cookie"
+)
+
+// IdentifyingHeaders is a list of response headers which reveal the server software
+// and its version. Use it as the Options.RemoveResponseHeaders value.
+var IdentifyingHeaders = []string{
+	"Server",
+	"X-Powered-By",
+	"X-AspNet-Version",
+	"X-AspNetMvc-Version",
+	"X-Runtime",
+	"X-Version",
+	"X-Generator",
+	"X-Drupal-Cache",
+	"X-Drupal-Dynamic-Cache",
+}
+
+// hardensResponse reports whether the options modify the response headers written by the handlers chain.
+func (o Options) hardensResponse() bool {
+	return len(o.RemoveResponseHeaders) > 0 || len(o.RewriteResponseHeaders) > 0 || o.NoStore || o.NoStoreOnSetCookie
+}
+
+// hardenResponseHeaders removes, rewrites and adds the response headers
+// based on the RemoveResponseHeaders, RewriteResponseHeaders, NoStore and NoStoreOnSetCookie options.
+func (s *Secure) hardenResponseHeaders(header http.Header) {
+	for _, name := range s.opt.RemoveResponseHeaders {
+		header.Del(name)
+	}
+
+	for name, value := range s.opt.RewriteResponseHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, value)
+		}
+	}
+
+	if s.opt.NoStore || (s.opt.NoStoreOnSetCookie && len(header.Values(setCookieHeader)) > 0) {
+		header.Set(cacheControlHeader, cacheControlValue)
+	}
+}
+
+// hookResponse registers the hardenResponseHeaders to run right before the response headers are sent,
+// that is after the handlers chain when the handlers do not write a body or a recorder is used,
+// or on the first body write otherwise.
+func (s *Secure) hookResponse(ctx iris.Context) {
+	w := ctx.ResponseWriter()
+	w.SetWriter(&headerHookWriter{
+		ResponseWriter: w.Naive(),
+		hook:           s.hardenResponseHeaders,
+	})
+}
+
+// headerHookWriter is an http.ResponseWriter which calls
+// its hook exactly once, before the status code and headers are sent.
+type headerHookWriter struct {
+	http.ResponseWriter
+	hook        func(http.Header)
+	wroteHeader bool
+}
+
+var (
+	_ http.Flusher  = (*headerHookWriter)(nil)
+	_ http.Hijacker = (*headerHookWriter)(nil)
+)
+
+func (w *headerHookWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.hook(w.ResponseWriter.Header())
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *headerHookWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerHookWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headerHookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, fmt.Errorf("secure: response writer does not support hijacking")
+}
+
+// Unwrap returns the underlying http.ResponseWriter, used by http.ResponseController.
+func (w *headerHookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,65 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestResponseHardening(t *testing.T) {
+	s := New(Options{
+		RemoveResponseHeaders:  IdentifyingHeaders,
+		RewriteResponseHeaders: map[string]string{"X-Backend": "app"},
+		NoStoreOnSetCookie:     true,
+	})
+	s.OverridePath("/account", func(o *Options) {
+		o.NoStore = true
+	})
+
+	app := iris.New()
+	app.Use(s.Handler)
+	app.Get("/", func(ctx iris.Context) {
+		ctx.Header("Server", "nginx/1.25.0")
+		ctx.Header("X-Powered-By", "PHP/8.3")
+		ctx.Header("X-Backend", "node-7")
+		ctx.WriteString("home")
+	})
+	app.Get("/login", func(ctx iris.Context) {
+		ctx.SetCookieKV("session", "value")
+		ctx.StatusCode(http.StatusNoContent)
+	})
+	app.Get("/account/stream", func(ctx iris.Context) {
+		ctx.Header("Cache-Control", "public, max-age=60")
+		ctx.WriteString("chunk")
+		ctx.ResponseWriter().Flush()
+		ctx.Header("Server", "late")
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("/")
+	expect(t, res.Body.String(), "home")
+	expect(t, res.Header().Get("Server"), "")
+	expect(t, res.Header().Get("X-Powered-By"), "")
+	expect(t, res.Header().Get("X-Backend"), "app")
+	expect(t, res.Header().Get("Cache-Control"), "")
+
+	res = serve("/login")
+	expect(t, res.Code, http.StatusNoContent)
+	expect(t, res.Header().Get("Cache-Control"), "no-store")
+	expect(t, res.Header().Get("X-Backend"), "")
+
+	res = serve("/account/stream")
+	expect(t, res.Body.String(), "chunk")
+	expect(t, res.Header().Get("Cache-Control"), "no-store")
+}
@@ -6,8 +6,10 @@ package secure
 import (
 	"fmt"
 	"net/http"
+	"net/netip"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/kataras/iris/v12"
 )
@@ -43,6 +45,9 @@ func defaultBadHostHandler(ctx iris.Context) {
 // Options is a struct for specifying configuration options for the secure.Secure middleware.
 type Options struct {
 	// If BrowserXSSFilter is true, adds the X-XSS-Protection header with the value `1; mode=block`. Default is false.
+	//
+	// Deprecated: modern browsers removed their XSS auditors and the header can introduce leaks on old ones,
+	// use a ContentSecurityPolicy (or CSP) instead.
 	BrowserXSSFilter bool // nolint: golint
 	// If ContentTypeNosniff is true, adds the X-Content-Type-Options header with the value `nosniff`. Default is false.
 	ContentTypeNosniff bool
@@ -69,18 +74,39 @@ type Options struct {
 	ContentSecurityPolicy string
 	// ContentSecurityPolicyReportOnly allows the Content-Security-Policy-Report-Only header value to be set with a custom value. Default is "".
 	ContentSecurityPolicyReportOnly string
+	// CSP is a typed Content-Security-Policy, see NewCSPBuilder. It overrides the ContentSecurityPolicy option. Default is nil.
+	CSP *CSPPolicy
+	// CSPReportOnly is a typed Content-Security-Policy-Report-Only, see NewCSPBuilder. It overrides the ContentSecurityPolicyReportOnly option. Default is nil.
+	CSPReportOnly *CSPPolicy
 	// CustomBrowserXSSValue allows the X-XSS-Protection header value to be set with a custom value. This overrides the BrowserXSSFilter option. Default is "".
+	//
+	// Deprecated: see BrowserXSSFilter.
 	CustomBrowserXSSValue string // nolint: golint
 	// Passing a template string will replace `$NONCE` with a dynamic nonce value of 16 bytes for each request which can be later retrieved using the Nonce function.
 	// Eg: script-src $NONCE -> script-src 'nonce-a2ZobGFoZg=='
 	// CustomFrameOptionsValue allows the X-Frame-Options header value to be set with a custom value. This overrides the FrameDeny option. Default is "".
 	CustomFrameOptionsValue string
 	// PublicKey implements HPKP to prevent MITM attacks with forged certificates. Default is "".
+	//
+	// Deprecated: HPKP is not supported by any browser and a wrong pin can lock out users, do not use it.
 	PublicKey string
 	// ReferrerPolicy allows sites to control when browsers will pass the Referer header to other sites. Default is "".
 	ReferrerPolicy string
 	// FeaturePolicy allows to selectively enable and disable use of various browser features and APIs. Default is "".
+	// Browsers which support the Permissions-Policy header ignore it.
+	//
+	// Deprecated: the Feature-Policy header was replaced by Permissions-Policy, use the PermissionsPolicy option instead.
 	FeaturePolicy string
+	// PermissionsPolicy allows to selectively enable and disable use of various browser features and APIs. Default is nil.
+	PermissionsPolicy PermissionsPolicy
+	// CrossOriginOpenerPolicy sets the Cross-Origin-Opener-Policy header, e.g. COOPSameOrigin. Default is "".
+	CrossOriginOpenerPolicy CrossOriginOpenerPolicy
+	// CrossOriginEmbedderPolicy sets the Cross-Origin-Embedder-Policy header, e.g. COEPRequireCorp. Default is "".
+	CrossOriginEmbedderPolicy CrossOriginEmbedderPolicy
+	// CrossOriginResourcePolicy sets the Cross-Origin-Resource-Policy header, e.g. CORPSameOrigin. Default is "".
+	CrossOriginResourcePolicy CrossOriginResourcePolicy
+	// PermittedCrossDomainPolicies sets the X-Permitted-Cross-Domain-Policies header, e.g. CrossDomainNone. Default is "".
+	PermittedCrossDomainPolicies PermittedCrossDomainPolicies
 	// SSLHost is the host name that is used to redirect http requests to https. Default is "", which indicates to use the same host.
 	SSLHost string
 	// AllowedHosts is a list of fully qualified domain names that are allowed. Default is empty list, which allows any and all host names.
@@ -93,10 +119,42 @@ type Options struct {
 	SSLHostFunc *SSLHostFunc
 	// STSSeconds is the max-age of the Strict-Transport-Security header. Default is 0, which would NOT include the header.
 	STSSeconds int64
+	// ReportingEndpoints is a map of Reporting API endpoint names and their URLs, e.g. {"csp-endpoint": "https://example.com/csp-reports"}.
+	// It is sent as the Reporting-Endpoints header, the names can be used by the CSP's "report-to" directive. Default is nil.
+	ReportingEndpoints map[string]string
 	// ExpectCTHeader allows the Expect-CT header value to be set with a custom value. Default is "".
+	//
+	// Deprecated: Certificate Transparency is enforced by browsers by default, the Expect-CT header is obsolete.
 	ExpectCTHeader string
 	// SecureContextKey allows a custom key to be specified for context storage.
 	SecureContextKey string
+	// TrustedProxies is a list of IPs and CIDRs (e.g. "10.0.0.0/8") of the reverse proxies and load balancers
+	// in front of the application. When set, the scheme and host of a request are resolved from the
+	// Forwarded (RFC 7239) or the ForwardedProtoHeader and ForwardedHostHeader headers,
+	// only if the request comes from a trusted proxy, and the Iris SSLProxyHeaders and HostProxyHeaders
+	// configuration is ignored. This affects the AllowedHosts, SSL redirect, SSLForceHost, STS and HPKP logic.
+	// Multi-valued headers are read from right to left, skipping the values of the trusted proxies
+	// found at the end of the X-Forwarded-For header, so the values appended by the client are ignored.
+	// Default is empty, which uses the Context's IsSSL and Host methods.
+	TrustedProxies []string
+	// ForwardedProtoHeader is the header name which holds the original scheme of a request sent by a trusted proxy.
+	// The Forwarded header takes precedence. Default is "X-Forwarded-Proto".
+	ForwardedProtoHeader string
+	// ForwardedHostHeader is the header name which holds the original host of a request sent by a trusted proxy.
+	// The Forwarded header takes precedence. Default is "X-Forwarded-Host".
+	ForwardedHostHeader string
+	// RemoveResponseHeaders is a list of response headers to remove from the responses of the handlers chain,
+	// e.g. the IdentifyingHeaders. Default is nil.
+	RemoveResponseHeaders []string
+	// RewriteResponseHeaders maps response headers to the values that replace the ones set by the handlers chain,
+	// e.g. {"Server": "server"}. Headers which are not set by the handlers chain are not added. Default is nil.
+	RewriteResponseHeaders map[string]string
+	// If NoStore is true, adds the Cache-Control header with the value `no-store` to the responses of the handlers chain.
+	// Use it with OverrideRoute or OverridePath to mark sensitive routes only. Default is false.
+	NoStore bool
+	// If NoStoreOnSetCookie is true, adds the Cache-Control header with the value `no-store` to the
+	// responses of the handlers chain which set cookies. Default is false.
+	NoStoreOnSetCookie bool
 }
 
 // Secure is a middleware that helps setup a few basic security features. A single secure.Options struct can be
@@ -108,12 +166,36 @@ type Secure struct {
 	// badHostHandler is the handler used when an incorrect host is passed in.
 	badHostHandler iris.Handler
 
+	// csp and cspReportOnly are the pre-rendered Content-Security-Policy
+	// and Content-Security-Policy-Report-Only header values.
+	csp, cspReportOnly *CSPPolicy
+
+	// reportingEndpoints is the pre-rendered Reporting-Endpoints header value.
+	reportingEndpoints string
+
+	// permissionsPolicy is the pre-rendered Permissions-Policy header value.
+	permissionsPolicy string
+
+	// hardensResponse reports whether the response headers of the handlers chain should be modified.
+	hardensResponse bool
+
+	// trustedProxies are the parsed TrustedProxies option.
+	trustedProxies []netip.Prefix
+	// forwardedProtoHeader and forwardedHostHeader hold the forwarded header names for trusted proxies.
+	forwardedProtoHeader, forwardedHostHeader string
+
 	// cRegexAllowedHosts saves the compiled regular expressions of the AllowedHosts
 	// option for subsequent use in processRequest
 	cRegexAllowedHosts []*regexp.Regexp
 
 	// ctxSecureHeaderKey is the key used for context storage for request modification.
 	ctxSecureHeaderKey string
+
+	mu sync.RWMutex
+	// overrides are the per-route and per-path options, see OverrideRoute.
+	overrides []override
+	// derived are the Secure instances built from the base options and each set of overrides, see addOverride.
+	derived map[string]*Secure
 }
 
 // New constructs a new Secure instance with the supplied options.
@@ -125,16 +207,35 @@ func New(options ...Options) *Secure {
 		o = options[0]
 	}
 
-	o.ContentSecurityPolicy = strings.Replace(o.ContentSecurityPolicy, "$NONCE", "'nonce-%[1]s'", -1)
-	o.ContentSecurityPolicyReportOnly = strings.Replace(o.ContentSecurityPolicyReportOnly, "$NONCE", "'nonce-%[1]s'", -1)
+	csp := o.CSP
+	if csp == nil && len(o.ContentSecurityPolicy) > 0 {
+		csp = ParseCSP(o.ContentSecurityPolicy)
+	}
+
+	cspReportOnly := o.CSPReportOnly
+	if cspReportOnly == nil && len(o.ContentSecurityPolicyReportOnly) > 0 {
+		cspReportOnly = ParseCSP(o.ContentSecurityPolicyReportOnly)
+	}
 
-	o.nonceEnabled = strings.Contains(o.ContentSecurityPolicy, "%[1]s") || strings.Contains(o.ContentSecurityPolicyReportOnly, "%[1]s")
+	o.nonceEnabled = (csp != nil && csp.HasNonce()) || (cspReportOnly != nil && cspReportOnly.HasNonce())
 
 	s := &Secure{
 		opt:            o,
 		badHostHandler: defaultBadHostHandler,
+		csp:            csp,
+		cspReportOnly:  cspReportOnly,
+	}
+
+	if len(o.ReportingEndpoints) > 0 {
+		s.reportingEndpoints = renderReportingEndpoints(o.ReportingEndpoints)
 	}
 
+	if len(o.PermissionsPolicy) > 0 {
+		s.permissionsPolicy = o.PermissionsPolicy.String()
+	}
+
+	s.hardensResponse = o.hardensResponse()
+
 	if s.opt.AllowedHostsAreRegex {
 		// Test for invalid regular expressions in AllowedHosts
 		for _, allowedHost := range o.AllowedHosts {
@@ -146,6 +247,24 @@ func New(options ...Options) *Secure {
 		}
 	}
 
+	if len(o.TrustedProxies) > 0 {
+		trustedProxies, err := parseTrustedProxies(o.TrustedProxies)
+		if err != nil {
+			panic(fmt.Sprintf("Error parsing TrustedProxies: %s", err))
+		}
+		s.trustedProxies = trustedProxies
+	}
+
+	s.forwardedProtoHeader = defaultForwardedProtoHeader
+	if len(o.ForwardedProtoHeader) > 0 {
+		s.forwardedProtoHeader = o.ForwardedProtoHeader
+	}
+
+	s.forwardedHostHeader = defaultForwardedHostHeader
+	if len(o.ForwardedHostHeader) > 0 {
+		s.forwardedHostHeader = o.ForwardedHostHeader
+	}
+
 	s.ctxSecureHeaderKey = ctxDefaultSecureHeaderKey
 	if len(s.opt.SecureContextKey) > 0 {
 		s.ctxSecureHeaderKey = s.opt.SecureContextKey
@@ -156,7 +275,10 @@ func New(options ...Options) *Secure {
 
 // SetBadHostHandler sets the handler to call when secure rejects the host name.
 func (s *Secure) SetBadHostHandler(handler iris.Handler) {
+	s.mu.Lock()
 	s.badHostHandler = handler
+	s.derived = s.buildDerived(s.overrides, handler)
+	s.mu.Unlock()
 }
 
 // Process runs the actual checks and writes the headers in the Context.
@@ -168,10 +290,15 @@ func (s *Secure) Process(ctx iris.Context) error {
 }
 
 // Handler is the main middleware.
+// When the RemoveResponseHeaders, RewriteResponseHeaders, NoStore or NoStoreOnSetCookie options are set,
+// it also modifies the response headers of the next handlers, right before they are sent to the client.
 func (s *Secure) Handler(ctx iris.Context) {
+	// Use the per-route options, if any.
+	d := s.resolve(ctx)
+
 	// Let secure process the request. If it returns an error,
 	// that indicates the request should not continue.
-	responseHeader, err := s.processRequest(ctx)
+	responseHeader, err := d.process(ctx)
 	addResponseHeaders(responseHeader, ctx)
 
 	// If there was an error, do not continue.
@@ -186,6 +313,10 @@ func (s *Secure) Handler(ctx iris.Context) {
 		return
 	}
 
+	if d.hardensResponse {
+		d.hookResponse(ctx)
+	}
+
 	ctx.Next()
 }
 
@@ -228,13 +359,19 @@ func (s *Secure) WithCSPNonce(ctx iris.Context, nonce string) {
 
 // processRequest runs the actual checks on the request and returns an error if the middleware chain should stop.
 func (s *Secure) processRequest(ctx iris.Context) (http.Header, error) {
+	// Use the per-route options, if any.
+	return s.resolve(ctx).process(ctx)
+}
+
+// process is processRequest without resolving the per-route options.
+func (s *Secure) process(ctx iris.Context) (http.Header, error) {
 	// Setup nonce if required.
 	if s.opt.nonceEnabled {
 		s.WithCSPNonce(ctx, cspRandNonce())
 	}
 
-	// Resolve the host for the request, using proxy headers if present.
-	host := ctx.Host()
+	// Resolve the scheme and host for the request, using proxy headers if present.
+	ssl, host := s.resolveSchemeAndHost(ctx)
 
 	// Allowed hosts check.
 	if len(s.opt.AllowedHosts) > 0 && !s.opt.IsDevelopment {
@@ -261,9 +398,6 @@ func (s *Secure) processRequest(ctx iris.Context) (http.Header, error) {
 		}
 	}
 
-	// Determine if we are on HTTPS.
-	ssl := ctx.IsSSL()
-
 	// SSL check.
 	if s.opt.SSLRedirect && !ssl && !s.opt.IsDevelopment {
 		r := ctx.Request()
@@ -356,21 +490,18 @@ func (s *Secure) processRequest(ctx iris.Context) (http.Header, error) {
 	}
 
 	// Content Security Policy header.
-	if len(s.opt.ContentSecurityPolicy) > 0 {
-		if s.opt.nonceEnabled {
-			responseHeader.Set(cspHeader, fmt.Sprintf(s.opt.ContentSecurityPolicy, CSPNonce(ctx)))
-		} else {
-			responseHeader.Set(cspHeader, s.opt.ContentSecurityPolicy)
-		}
+	if s.csp != nil {
+		responseHeader.Set(cspHeader, s.csp.Render(CSPNonce(ctx)))
 	}
 
 	// Content Security Policy Report Only header.
-	if len(s.opt.ContentSecurityPolicyReportOnly) > 0 {
-		if s.opt.nonceEnabled {
-			responseHeader.Set(cspReportOnlyHeader, fmt.Sprintf(s.opt.ContentSecurityPolicyReportOnly, CSPNonce(ctx)))
-		} else {
-			responseHeader.Set(cspReportOnlyHeader, s.opt.ContentSecurityPolicyReportOnly)
-		}
+	if s.cspReportOnly != nil {
+		responseHeader.Set(cspReportOnlyHeader, s.cspReportOnly.Render(CSPNonce(ctx)))
+	}
+
+	// Reporting Endpoints header.
+	if len(s.reportingEndpoints) > 0 {
+		responseHeader.Set(reportingEndpointsHeader, s.reportingEndpoints)
 	}
 
 	// Referrer Policy header.
@@ -378,11 +509,34 @@ func (s *Secure) processRequest(ctx iris.Context) (http.Header, error) {
 		responseHeader.Set(referrerPolicyHeader, s.opt.ReferrerPolicy)
 	}
 
-	// Feature Policy header.
+	// Permissions Policy header.
+	if len(s.permissionsPolicy) > 0 {
+		responseHeader.Set(permissionsPolicyHeader, s.permissionsPolicy)
+	}
+
+	// Feature Policy header, browsers which support Permissions Policy ignore it.
 	if len(s.opt.FeaturePolicy) > 0 {
 		responseHeader.Set(featurePolicyHeader, s.opt.FeaturePolicy)
 	}
 
+	// Cross-Origin Opener, Embedder and Resource Policy headers.
+	if len(s.opt.CrossOriginOpenerPolicy) > 0 {
+		responseHeader.Set(coopHeader, string(s.opt.CrossOriginOpenerPolicy))
+	}
+
+	if len(s.opt.CrossOriginEmbedderPolicy) > 0 {
+		responseHeader.Set(coepHeader, string(s.opt.CrossOriginEmbedderPolicy))
+	}
+
+	if len(s.opt.CrossOriginResourcePolicy) > 0 {
+		responseHeader.Set(corpHeader, string(s.opt.CrossOriginResourcePolicy))
+	}
+
+	// X-Permitted-Cross-Domain-Policies header.
+	if len(s.opt.PermittedCrossDomainPolicies) > 0 {
+		responseHeader.Set(permittedCrossDomainPolicies, string(s.opt.PermittedCrossDomainPolicies))
+	}
+
 	// Expect-CT header.
 	if len(s.opt.ExpectCTHeader) > 0 {
 		responseHeader.Set(expectCTHeader, s.opt.ExpectCTHeader)
@@ -0,0 +1,213 @@
+package secure
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// hstsPreloadMinSeconds is the minimum max-age accepted by the HSTS preload list (one year).
+// See https://hstspreload.org/#submission-requirements.
+const hstsPreloadMinSeconds = 31536000
+
+var referrerPolicies = map[string]struct{}{
+	"no-referrer":                     {},
+	"no-referrer-when-downgrade":      {},
+	"origin":                          {},
+	"origin-when-cross-origin":        {},
+	"same-origin":                     {},
+	"strict-origin":                   {},
+	"strict-origin-when-cross-origin": {},
+	"unsafe-url":                      {},
+}
+
+// OptionError describes a misconfigured Options field.
+type OptionError struct {
+	// Field is the name of the Options field, e.g. "STSPreload".
+	Field string
+	// Reason describes what is wrong with the field.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("secure: %s: %s", e.Field, e.Reason)
+}
+
+// Validate reports misconfigured and contradictory options, e.g. an STSPreload
+// which does not meet the HSTS preload list requirements or an invalid AllowedHosts regular expression.
+// The returned error joins one *OptionError per problem, use errors.As to inspect them.
+//
+// See NewValidated too.
+func (o Options) Validate() error {
+	var errs []error
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, &OptionError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// Hosts.
+	if o.AllowedHostsAreRegex {
+		if len(o.AllowedHosts) == 0 {
+			add("AllowedHostsAreRegex", "has no effect without AllowedHosts")
+		}
+
+		for _, allowedHost := range o.AllowedHosts {
+			if _, err := regexp.Compile(fmt.Sprintf("^%s$", allowedHost)); err != nil {
+				add("AllowedHosts", "invalid regular expression %q: %v", allowedHost, err)
+			}
+		}
+	}
+
+	for _, host := range o.AllowedHosts {
+		if host == "" {
+			add("AllowedHosts", "empty host name")
+		}
+	}
+
+	// SSL.
+	if strings.Contains(o.SSLHost, "/") {
+		add("SSLHost", "must be a host name, not a URL: %q", o.SSLHost)
+	}
+
+	if o.SSLForceHost && o.SSLHost == "" && o.SSLHostFunc == nil {
+		add("SSLForceHost", "has no effect without SSLHost or SSLHostFunc")
+	}
+
+	if o.SSLTemporaryRedirect && !o.SSLRedirect && !o.SSLForceHost {
+		add("SSLTemporaryRedirect", "has no effect without SSLRedirect or SSLForceHost")
+	}
+
+	// HSTS.
+	if o.STSSeconds < 0 {
+		add("STSSeconds", "must not be negative: %d", o.STSSeconds)
+	}
+
+	if o.STSSeconds == 0 {
+		if o.STSIncludeSubdomains {
+			add("STSIncludeSubdomains", "has no effect without STSSeconds")
+		}
+
+		if o.STSPreload {
+			add("STSPreload", "has no effect without STSSeconds")
+		}
+
+		if o.ForceSTSHeader {
+			add("ForceSTSHeader", "has no effect without STSSeconds")
+		}
+	} else if o.STSPreload {
+		// HSTS preload list eligibility.
+		if o.STSSeconds < hstsPreloadMinSeconds {
+			add("STSPreload", "requires STSSeconds of at least %d (one year) to be eligible for the HSTS preload list, got %d", hstsPreloadMinSeconds, o.STSSeconds)
+		}
+
+		if !o.STSIncludeSubdomains {
+			add("STSPreload", "requires STSIncludeSubdomains to be eligible for the HSTS preload list")
+		}
+
+		if !o.SSLRedirect {
+			add("STSPreload", "requires SSLRedirect to be eligible for the HSTS preload list")
+		}
+	}
+
+	// Frame options.
+	if v := o.CustomFrameOptionsValue; v != "" && !strings.EqualFold(v, "DENY") && !strings.EqualFold(v, "SAMEORIGIN") {
+		add("CustomFrameOptionsValue", "must be DENY or SAMEORIGIN, got %q, use the frame-ancestors CSP directive to allow specific origins", v)
+	}
+
+	// Content Security Policy.
+	if o.CSP != nil && o.ContentSecurityPolicy != "" {
+		add("ContentSecurityPolicy", "is ignored because CSP is set")
+	}
+
+	if o.CSPReportOnly != nil && o.ContentSecurityPolicyReportOnly != "" {
+		add("ContentSecurityPolicyReportOnly", "is ignored because CSPReportOnly is set")
+	}
+
+	for _, policy := range []struct {
+		field  string
+		policy *CSPPolicy
+	}{{"CSP", o.CSP}, {"CSPReportOnly", o.CSPReportOnly}} {
+		if policy.policy == nil || policy.policy.ReportTo() == "" {
+			continue
+		}
+
+		if _, ok := o.ReportingEndpoints[policy.policy.ReportTo()]; !ok {
+			add(policy.field, "report-to group %q is missing from ReportingEndpoints", policy.policy.ReportTo())
+		}
+	}
+
+	// Reporting endpoints.
+	for name, endpoint := range o.ReportingEndpoints {
+		if name == "" || strings.ContainsAny(name, " \t,;=\"") {
+			add("ReportingEndpoints", "invalid endpoint name %q", name)
+		}
+
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && !o.IsDevelopment) {
+			add("ReportingEndpoints", "endpoint %q must be an absolute https URL, got %q", name, endpoint)
+		}
+	}
+
+	// Other headers.
+	if o.ReferrerPolicy != "" {
+		for _, policy := range strings.Split(o.ReferrerPolicy, ",") {
+			if _, ok := referrerPolicies[strings.TrimSpace(policy)]; !ok {
+				add("ReferrerPolicy", "unknown policy %q", policy)
+			}
+		}
+	}
+
+	for feature, allowlist := range o.PermissionsPolicy {
+		if feature == "" || strings.ContainsAny(feature, " \t,;=()\"") {
+			add("PermissionsPolicy", "invalid feature name %q", feature)
+		}
+
+		for _, member := range allowlist {
+			switch member {
+			case PermissionsSelf, PermissionsAll, PermissionsSrc:
+			default:
+				if u, err := url.Parse(member); err != nil || u.Scheme == "" || u.Host == "" || strings.ContainsAny(member, " \"") {
+					add("PermissionsPolicy", "feature %q: invalid origin %q", feature, member)
+				}
+			}
+		}
+	}
+
+	// Response headers.
+	for name, value := range o.RewriteResponseHeaders {
+		if value == "" {
+			add("RewriteResponseHeaders", "empty value for %q, use RemoveResponseHeaders instead", name)
+		}
+	}
+
+	// Proxies.
+	if _, err := parseTrustedProxies(o.TrustedProxies); err != nil {
+		add("TrustedProxies", "%v", err)
+	}
+
+	if len(o.TrustedProxies) == 0 {
+		if o.ForwardedProtoHeader != "" {
+			add("ForwardedProtoHeader", "has no effect without TrustedProxies")
+		}
+
+		if o.ForwardedHostHeader != "" {
+			add("ForwardedHostHeader", "has no effect without TrustedProxies")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewValidated same as New but it validates the options first and
+// returns the validation error instead of a Secure instance on misconfiguration.
+//
+// See Options.Validate.
+func NewValidated(options Options) (*Secure, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	return New(options), nil
+}
@@ -0,0 +1,84 @@
+package secure
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPresetsAreValid(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"strict":         StrictPreset(),
+		"api":            APIPreset(),
+		"legacy-browser": LegacyBrowserPreset(),
+	} {
+		if _, err := NewValidated(opts); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestPresetsDoNotShareSlices(t *testing.T) {
+	identifying := append([]string(nil), IdentifyingHeaders...)
+
+	strict := StrictPreset()
+	strict.RemoveResponseHeaders[0] = "X-Custom"
+	strict.RemoveResponseHeaders = append(strict.RemoveResponseHeaders[:1], "X-Other")
+
+	expect(t, IdentifyingHeaders[0], identifying[0])
+	expect(t, len(IdentifyingHeaders), len(identifying))
+	expect(t, APIPreset().RemoveResponseHeaders[0], identifying[0])
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		options Options
+		fields  []string
+	}{
+		{Options{STSSeconds: 300, STSPreload: true, SSLRedirect: true}, []string{"STSPreload", "STSPreload"}},
+		{Options{STSSeconds: hstsPreloadMinSeconds, STSPreload: true, STSIncludeSubdomains: true}, []string{"STSPreload"}},
+		{Options{STSPreload: true, STSIncludeSubdomains: true}, []string{"STSIncludeSubdomains", "STSPreload"}},
+		{Options{AllowedHostsAreRegex: true, AllowedHosts: []string{"(www.example.com"}}, []string{"AllowedHosts"}},
+		{Options{SSLForceHost: true}, []string{"SSLForceHost"}},
+		{Options{SSLHost: "https://example.com"}, []string{"SSLHost"}},
+		{Options{CustomFrameOptionsValue: "ALLOW-FROM https://example.com"}, []string{"CustomFrameOptionsValue"}},
+		{Options{
+			CSP:                NewCSPBuilder().Add(CSPDefaultSrc, CSPSelf).ReportTo("csp").MustBuild(),
+			ReportingEndpoints: map[string]string{"default": "/reports"},
+		}, []string{"CSP", "ReportingEndpoints"}},
+		{Options{ReferrerPolicy: "no-referer"}, []string{"ReferrerPolicy"}},
+		{Options{PermissionsPolicy: PermissionsPolicy{"camera": {"example.com"}}}, []string{"PermissionsPolicy"}},
+		{Options{TrustedProxies: []string{"10.0.0"}}, []string{"TrustedProxies"}},
+		{Options{ForwardedProtoHeader: "X-Scheme"}, []string{"ForwardedProtoHeader"}},
+		{Options{RewriteResponseHeaders: map[string]string{"Server": ""}}, []string{"RewriteResponseHeaders"}},
+	}
+
+	for i, c := range cases {
+		err := c.options.Validate()
+		if err == nil {
+			t.Errorf("[%d] expected error", i)
+			continue
+		}
+
+		var fields []string
+		for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+			var optErr *OptionError
+			if !errors.As(e, &optErr) {
+				t.Fatalf("[%d] unexpected error type: %T", i, e)
+			}
+			fields = append(fields, optErr.Field)
+		}
+
+		if len(fields) != len(c.fields) {
+			t.Errorf("[%d] expected errors for %v but got: %v", i, c.fields, err)
+			continue
+		}
+
+		for j := range fields {
+			expect(t, fields[j], c.fields[j])
+		}
+	}
+
+	if err := (Options{}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,236 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"sync"
+
+	"github.com/kataras/iris/v12"
+)
+
+const cspExtraSourcesKey = "iris.secure.csp.extra"
+
+// Template functions registered by the ViewEngine method.
+const (
+	// ViewCSPNonce is the name of a template function which returns the current request's CSP nonce value.
+	ViewCSPNonce = "csp_nonce"
+	// ViewCSPNonceAttr is the name of a template function which returns the current request's
+	// `nonce="..."` attribute, or nothing if the policy has no nonce source.
+	ViewCSPNonceAttr = "csp_nonce_attr"
+	// ViewCSPScript is the name of a template function which accepts an inline script's source,
+	// allows its sha256 hash through the current response's CSP and returns the `<script>` element.
+	ViewCSPScript = "csp_script"
+	// ViewCSPStyle is the name of a template function which accepts an inline style's source,
+	// allows its sha256 hash through the current response's CSP and returns the `<style>` element.
+	ViewCSPStyle = "csp_style"
+)
+
+// ViewEngine registers the CSP template functions to the "engine", e.g. iris.HTML, iris.Django or iris.Pug,
+// and returns the engine to register to the application, so templates can mark inline scripts and styles
+// as trusted without 'unsafe-inline'. The functions work with any view data or view model.
+// It panics if the engine does not support template functions.
+//
+// The functions cannot access the current request, so they render placeholders which are replaced
+// when the view is rendered through Context.View: the rendered view is buffered, the hashes
+// of its inline scripts and styles are added to the CSP headers and the placeholders are replaced
+// by the request's nonce, before the view is written to the client.
+//
+// Usage:
+//
+//	app.RegisterView(s.ViewEngine(iris.HTML("./views", ".html")))
+//
+// HTML templates:
+//
+//	<script {{ csp_nonce_attr }} src="/app.js"></script>
+//	{{ csp_script "document.body.classList.add('js')" }}
+//
+// Django templates:
+//
+//	<script {{ csp_nonce_attr()|safe }} src="/app.js"></script>
+//	{{ csp_script("document.body.classList.add('js')")|safe }}
+//
+// Pug templates:
+//
+//	script(nonce=csp_nonce src="/app.js")
+//	| {{ csp_script "document.body.classList.add('js')" }}
+//
+// The csp_script and csp_style functions add a `'sha256-...'` source to the script-src-elem and style-src-elem
+// directives or to the directives they fall back to (script-src, style-src, default-src) of both
+// the enforced and the report-only policies. Directives set to 'none' are not modified.
+func (s *Secure) ViewEngine(engine iris.ViewEngine) iris.ViewEngine {
+	funcer, ok := engine.(interface {
+		AddFunc(funcName string, funcBody interface{})
+	})
+	if !ok {
+		panic(fmt.Sprintf("secure: view engine %q does not support template functions", engine.Name()))
+	}
+
+	e := &viewEngine{ViewEngine: engine, secure: s, marker: newViewMarker()}
+
+	funcer.AddFunc(ViewCSPNonce, func() string {
+		return e.placeholder(viewNonce, "")
+	})
+	funcer.AddFunc(ViewCSPNonceAttr, func() template.HTMLAttr {
+		return template.HTMLAttr(e.placeholder(viewNonceAttr, ""))
+	})
+	funcer.AddFunc(ViewCSPScript, func(content string) template.HTML {
+		hash := CSPHash(CSPSHA256, []byte(content))
+		return template.HTML(e.placeholder(viewScriptHash, string(hash)) + "<script>" + content + "</script>")
+	})
+	funcer.AddFunc(ViewCSPStyle, func(content string) template.HTML {
+		hash := CSPHash(CSPSHA256, []byte(content))
+		return template.HTML(e.placeholder(viewStyleHash, string(hash)) + "<style>" + content + "</style>")
+	})
+
+	return e
+}
+
+// The kinds of the view placeholders.
+const (
+	viewNonce      = 'n'
+	viewNonceAttr  = 'a'
+	viewScriptHash = 's'
+	viewStyleHash  = 'c'
+)
+
+// viewPlaceholderEnd terminates the value of a placeholder,
+// it's not part of the base64 alphabet of the hashes.
+const viewPlaceholderEnd = '~'
+
+// newViewMarker returns the random prefix of the view placeholders,
+// so the placeholders cannot be forged by user content rendered in a view.
+func newViewMarker() []byte {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("secure: view marker: %v", err))
+	}
+
+	return []byte("csp" + hex.EncodeToString(b))
+}
+
+// viewEngine is the view engine returned by Secure.ViewEngine.
+type viewEngine struct {
+	iris.ViewEngine
+	secure *Secure
+	marker []byte
+}
+
+var viewBufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// AddFunc forwards the template functions registered by the application, e.g. the url and tr ones.
+func (e *viewEngine) AddFunc(funcName string, funcBody interface{}) {
+	e.ViewEngine.(interface {
+		AddFunc(funcName string, funcBody interface{})
+	}).AddFunc(funcName, funcBody)
+}
+
+func (e *viewEngine) placeholder(kind byte, value string) string {
+	return string(e.marker) + string(kind) + value + string(viewPlaceholderEnd)
+}
+
+// ExecuteWriter renders the view to a buffer, applies the CSP changes of its placeholders
+// to the current response, when "w" is the request's Context, and writes the view to "w".
+func (e *viewEngine) ExecuteWriter(w io.Writer, filename string, layout string, bindingData interface{}) error {
+	buf := viewBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer viewBufferPool.Put(buf)
+
+	if err := e.ViewEngine.ExecuteWriter(buf, filename, layout, bindingData); err != nil {
+		return err
+	}
+
+	ctx, _ := w.(iris.Context)
+	body, scripts, styles := e.replacePlaceholders(ctx, buf.Bytes())
+	if ctx != nil {
+		if len(scripts) > 0 {
+			e.secure.AllowInline(ctx, CSPScriptSrcElem, scripts...)
+		}
+
+		if len(styles) > 0 {
+			e.secure.AllowInline(ctx, CSPStyleSrcElem, styles...)
+		}
+	}
+
+	_, err := w.Write(body)
+	return err
+}
+
+// replacePlaceholders replaces the placeholders of the rendered view
+// and returns the hashes of the inline scripts and styles.
+func (e *viewEngine) replacePlaceholders(ctx iris.Context, view []byte) (body []byte, scripts, styles []CSPSource) {
+	i := bytes.Index(view, e.marker)
+	if i == -1 {
+		return view, nil, nil
+	}
+
+	var nonce string
+	if ctx != nil {
+		nonce = CSPNonce(ctx)
+	}
+
+	body = make([]byte, 0, len(view))
+	for i != -1 {
+		body = append(body, view[:i]...)
+		view = view[i+len(e.marker):]
+
+		end := bytes.IndexByte(view, viewPlaceholderEnd)
+		if end < 1 {
+			// not a placeholder, keep it as it is.
+			body = append(body, e.marker...)
+			i = bytes.Index(view, e.marker)
+			continue
+		}
+
+		kind, value := view[0], string(view[1:end])
+		view = view[end+1:]
+
+		switch kind {
+		case viewNonce:
+			body = append(body, nonce...)
+		case viewNonceAttr:
+			if nonce != "" {
+				body = append(body, `nonce="`+nonce+`"`...)
+			}
+		case viewScriptHash:
+			scripts = append(scripts, CSPSource(value))
+		case viewStyleHash:
+			styles = append(styles, CSPSource(value))
+		}
+
+		i = bytes.Index(view, e.marker)
+	}
+
+	body = append(body, view...)
+	return body, scripts, styles
+}
+
+// AllowInline adds the "sources" (e.g. a CSPHash) to the "directive" of the current response's
+// Content-Security-Policy and Content-Security-Policy-Report-Only headers.
+// If the directive is missing, the one it falls back to is modified instead.
+// It must be called before the response headers are written, see ViewEngine.
+func (s *Secure) AllowInline(ctx iris.Context, directive CSPDirective, sources ...CSPSource) {
+	var extra map[CSPDirective][]CSPSource
+	if v, ok := ctx.Values().Get(cspExtraSourcesKey).(map[CSPDirective][]CSPSource); ok {
+		extra = v
+	} else {
+		extra = make(map[CSPDirective][]CSPSource)
+		ctx.Values().Set(cspExtraSourcesKey, extra)
+	}
+	extra[directive] = append(extra[directive], sources...)
+
+	d := s.resolve(ctx)
+	nonce := CSPNonce(ctx)
+	header := ctx.ResponseWriter().Header()
+
+	if d.csp != nil {
+		header.Set(cspHeader, d.csp.renderWith(nonce, extra))
+	}
+
+	if d.cspReportOnly != nil {
+		header.Set(cspReportOnlyHeader, d.cspReportOnly.renderWith(nonce, extra))
+	}
+}
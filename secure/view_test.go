@@ -0,0 +1,85 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestViewEngine(t *testing.T) {
+	const inline = "document.body.classList.add('js')"
+
+	templates := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<script {{ csp_nonce_attr }} src="/app.js"></script>{{ csp_script "` + inline + `" }}{{ csp_style "body{}" }}{{ .Title }}`)},
+		"index.django": &fstest.MapFile{Data: []byte(
+			`<script {{ csp_nonce_attr()|safe }} src="/app.js"></script>{{ csp_script("` + inline + `")|safe }}{{ csp_style("body{}")|safe }}{{ Title }}`)},
+		"index.pug": &fstest.MapFile{Data: []byte(
+			"script(nonce=csp_nonce src=\"/app.js\")\n| {{ csp_script \"" + inline + "\" }}{{ csp_style \"body{}\" }}{{ .Title }}\n")},
+	}
+
+	scriptHash := string(CSPHash(CSPSHA256, []byte(inline)))
+	styleHash := string(CSPHash(CSPSHA256, []byte("body{}")))
+
+	for _, engine := range []iris.ViewEngine{
+		iris.HTML(templates, ".html"),
+		iris.Django(templates, ".django"),
+		iris.Pug(templates, ".pug"),
+	} {
+		t.Run(engine.Name(), func(t *testing.T) {
+			s := New(Options{
+				ContentSecurityPolicy:           "default-src 'self'; script-src $NONCE 'unsafe-inline'",
+				ContentSecurityPolicyReportOnly: "default-src 'self'; object-src 'none'",
+			})
+
+			app := iris.New()
+			app.RegisterView(s.ViewEngine(engine))
+			app.Use(s.Handler)
+			app.Get("/", func(ctx iris.Context) {
+				// a view model, not view data.
+				if err := ctx.View("index"+engine.Ext(), iris.Map{"Title": "home"}); err != nil {
+					t.Error(err)
+				}
+			})
+			if err := app.Build(); err != nil {
+				t.Fatal(err)
+			}
+
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			app.ServeHTTP(res, req)
+			expect(t, res.Code, http.StatusOK)
+
+			body := res.Body.String()
+			parts := strings.Split(body, `nonce="`)
+			if len(parts) < 2 {
+				t.Fatalf("missing nonce: %s", body)
+			}
+			nonce := strings.Split(parts[1], `"`)[0]
+			if nonce == "" {
+				t.Fatalf("missing nonce: %s", body)
+			}
+
+			expect(t, body, `<script nonce="`+nonce+`" src="/app.js"></script><script>`+inline+`</script><style>body{}</style>home`)
+			expect(t, res.Header().Get("Content-Security-Policy"),
+				"default-src 'self' "+styleHash+"; script-src 'nonce-"+nonce+"' 'unsafe-inline' "+scriptHash)
+			expect(t, res.Header().Get("Content-Security-Policy-Report-Only"),
+				"default-src 'self' "+scriptHash+" "+styleHash+"; object-src 'none'")
+		})
+	}
+}
+
+func TestViewEngineForgedPlaceholder(t *testing.T) {
+	s := New(Options{ContentSecurityPolicy: "script-src $NONCE"})
+	e := s.ViewEngine(iris.HTML(fstest.MapFS{}, ".html")).(*viewEngine)
+
+	// user content cannot guess the random marker.
+	forged := []byte("csp00000000000000000000000000000000n~")
+	body, scripts, _ := e.replacePlaceholders(nil, forged)
+	expect(t, string(body), string(forged))
+	expect(t, len(scripts), 0)
+}
@@ -36,6 +36,10 @@ func main() {
 // The iris-contrib/throttler middleare is the equivalent of:
 // https://github.com/throttled/throttled/blob/master/http.go.
 func newRateLimiterMiddleware(maxRatePerMinute int) iris.Handler {
+	// The memstore keeps the limits per instance. To share the limits
+	// between multiple instances use a throttler.Store instead, e.g.
+	// backend, err := throttler.NewFileBackend("/var/run/app/ratelimit.json")
+	// store := throttler.NewStore(backend, "limited:")
 	store, err := memstore.New(65536)
 	if err != nil {
 		panic(err)
@@ -54,6 +58,8 @@ func newRateLimiterMiddleware(maxRatePerMinute int) iris.Handler {
 	httpRateLimiter := throttler.RateLimiter{
 		RateLimiter: rateLimiter,
 		VaryBy:      &throttled.VaryBy{Path: true},
+		// Or use an Iris-aware key, e.g.
+		// KeyFunc: throttler.Compose(throttler.ByIP(), throttler.ByRoute()),
 	}
 
 	return httpRateLimiter.RateLimit
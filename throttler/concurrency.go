@@ -0,0 +1,189 @@
+package throttler
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/ratelimit"
+)
+
+// DefaultConcurrencyDeniedHandler is the default DeniedHandler for a
+// ConcurrencyLimiter. It returns a 429 status code with a JSON problem body.
+var DefaultConcurrencyDeniedHandler = func(ctx iris.Context) {
+	ctx.StopExecution()
+	ctx.Problem(iris.NewProblem().
+		Status(http.StatusTooManyRequests).
+		Detail("too many concurrent requests").
+		Key("policy", ConcurrencyPolicyName))
+}
+
+// ConcurrencyPolicyName is the policy name reported by the DefaultConcurrencyDeniedHandler.
+const ConcurrencyPolicyName = "concurrency"
+
+// ConcurrencyLimiter limits the number of requests of a key which are served at the same time,
+// e.g. to protect expensive export routes. It can be combined with a RateLimiter:
+//
+//	app.Get("/export", rateLimiter.RateLimit, concurrencyLimiter.Limit, exportHandler)
+//
+// A ConcurrencyLimiter must not be copied after first use.
+type ConcurrencyLimiter struct {
+	// MaxInFlight is the maximum number of requests of a key which are served
+	// at the same time. It must be set.
+	MaxInFlight int
+
+	// MaxQueue is the maximum number of requests of a key which wait for
+	// another request to complete, once MaxInFlight is reached.
+	// If it is zero, those requests are denied immediately.
+	MaxQueue int
+
+	// QueueTimeout is the maximum time a request waits in the queue before
+	// it is denied. If it is zero, it waits until the client goes away.
+	QueueTimeout time.Duration
+
+	// KeyFunc is called for each request to generate a key for the
+	// limiter. If it is nil, all requests use an empty string key.
+	KeyFunc KeyFunc
+
+	// Observers are notified of each decision, the policy of the events is ConcurrencyPolicyName.
+	Observers []Observer
+
+	// DeniedHandler is called if the request is disallowed. If it is
+	// nil, the DefaultConcurrencyDeniedHandler variable is used.
+	DeniedHandler iris.Handler
+
+	// Error is called if the ConcurrencyLimiter is misconfigured. If it is
+	// nil, the DefaultError is used.
+	Error func(ctx iris.Context, err error)
+
+	mu      sync.Mutex
+	buckets map[string]*concurrencyBucket
+}
+
+type concurrencyBucket struct {
+	slots chan struct{}
+	refs  int // in-flight and queued requests, guarded by the limiter's mutex.
+}
+
+// Limit is an Iris middleware that limits the number of concurrent requests.
+// Requests which get a slot are passed to the next handler and release it when it returns,
+// queued requests wait for a slot and denied requests are passed to the DeniedHandler.
+func (c *ConcurrencyLimiter) Limit(ctx iris.Context) {
+	if c.MaxInFlight <= 0 {
+		e := c.Error
+		if e == nil {
+			e = DefaultError
+		}
+		e(ctx, errors.New("you must set a MaxInFlight on ConcurrencyLimiter"))
+		return
+	}
+
+	var k string
+	if c.KeyFunc != nil {
+		k = c.KeyFunc(ctx)
+	}
+
+	bucket, ok := c.enter(k)
+	if ok && !c.acquire(ctx, bucket) {
+		c.leave(k, bucket)
+		ok = false
+	}
+
+	ratelimit.Notify(c.Observers, ctx, Event{Policy: ConcurrencyPolicyName, Key: k, Limited: !ok})
+
+	if !ok {
+		c.deny(ctx)
+		return
+	}
+
+	defer func() {
+		<-bucket.slots
+		c.leave(k, bucket)
+	}()
+
+	ctx.Next()
+}
+
+// InFlight returns the number of in-flight and queued requests of the key.
+func (c *ConcurrencyLimiter) InFlight(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if bucket, ok := c.buckets[key]; ok {
+		return bucket.refs
+	}
+
+	return 0
+}
+
+// enter reserves a place for the request in the bucket of the key,
+// it reports false if MaxInFlight and MaxQueue are reached.
+func (c *ConcurrencyLimiter) enter(key string) (*concurrencyBucket, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.buckets == nil {
+		c.buckets = make(map[string]*concurrencyBucket)
+	}
+
+	bucket, ok := c.buckets[key]
+	if !ok {
+		bucket = &concurrencyBucket{slots: make(chan struct{}, c.MaxInFlight)}
+		c.buckets[key] = bucket
+	}
+
+	if bucket.refs >= c.MaxInFlight+c.MaxQueue {
+		return nil, false
+	}
+
+	bucket.refs++
+	return bucket, true
+}
+
+func (c *ConcurrencyLimiter) leave(key string, bucket *concurrencyBucket) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if bucket.refs--; bucket.refs == 0 {
+		delete(c.buckets, key)
+	}
+}
+
+// acquire takes a slot of the bucket, waiting in the queue if MaxQueue is set.
+func (c *ConcurrencyLimiter) acquire(ctx iris.Context, bucket *concurrencyBucket) bool {
+	select {
+	case bucket.slots <- struct{}{}:
+		return true
+	default:
+		if c.MaxQueue <= 0 {
+			return false
+		}
+	}
+
+	var timeout <-chan time.Time
+	if c.QueueTimeout > 0 {
+		timer := time.NewTimer(c.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case bucket.slots <- struct{}{}:
+		return true
+	case <-ctx.Request().Context().Done():
+		return false
+	case <-timeout:
+		return false
+	}
+}
+
+func (c *ConcurrencyLimiter) deny(ctx iris.Context) {
+	dh := c.DeniedHandler
+	if dh == nil {
+		dh = DefaultConcurrencyDeniedHandler
+	}
+	dh(ctx)
+}
@@ -0,0 +1,164 @@
+package throttler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+)
+
+func TestRouteCosts(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, throttled.RateQuota{MaxRate: throttled.PerMin(10), MaxBurst: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := &throttler.RateLimiter{
+		RateLimiter: rateLimiter,
+		Cost:        throttler.RouteCosts(map[string]int{"GET/search": 4}, 1),
+	}
+
+	app := iris.New()
+	app.Use(limiter.RateLimit)
+	app.Get("/search", func(ctx iris.Context) {})
+	app.Get("/read", func(ctx iris.Context) {})
+	if err = app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	expectHeader(t, serve("/search"), "X-RateLimit-Remaining", "6")
+	expectHeader(t, serve("/read"), "X-RateLimit-Remaining", "5")
+	expectHeader(t, serve("/search"), "X-RateLimit-Remaining", "1")
+
+	if res := serve("/search"); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status code %d but got %d", http.StatusTooManyRequests, res.Code)
+	}
+
+	if res := serve("/read"); res.Code != http.StatusOK {
+		t.Fatalf("expected status code %d but got %d", http.StatusOK, res.Code)
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := &throttler.ConcurrencyLimiter{
+		MaxInFlight:  1,
+		MaxQueue:     1,
+		QueueTimeout: time.Second,
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	app := iris.New()
+	app.Get("/", limiter.Limit, func(ctx iris.Context) {
+		started <- struct{}{}
+		<-release
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func() int {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		app.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	var (
+		wg    sync.WaitGroup
+		codes = make([]int, 2)
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes[0] = serve()
+	}()
+	<-started
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes[1] = serve()
+	}()
+
+	for limiter.InFlight("") != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if code := serve(); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the request to be denied when the queue is full but got %d", code)
+	}
+
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("[%d] expected status code %d but got %d", i, http.StatusOK, code)
+		}
+	}
+
+	if n := limiter.InFlight(""); n != 0 {
+		t.Fatalf("expected no in-flight requests but got %d", n)
+	}
+}
+
+func TestConcurrencyLimiterQueueTimeout(t *testing.T) {
+	limiter := &throttler.ConcurrencyLimiter{
+		MaxInFlight:  1,
+		MaxQueue:     1,
+		QueueTimeout: 20 * time.Millisecond,
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	app := iris.New()
+	app.Get("/", limiter.Limit, func(ctx iris.Context) {
+		close(started)
+		<-release
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-started
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the queued request to time out but got %d", res.Code)
+	}
+
+	close(release)
+	<-done
+}
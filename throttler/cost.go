@@ -0,0 +1,44 @@
+package throttler
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+// CostFunc returns the quantity a request counts against the limits, see the RateLimiter.Cost field.
+type CostFunc func(ctx iris.Context) int
+
+// FixedCost returns a CostFunc which always returns "cost".
+func FixedCost(cost int) CostFunc {
+	return func(iris.Context) int {
+		return cost
+	}
+}
+
+// RouteCosts returns a CostFunc which returns the cost of the current route
+// by its name (e.g. "GET/search") or its path template (e.g. "/export/{format}"),
+// or the "defaultCost" if the route is not listed.
+//
+// Usage:
+//
+//	limiter.Cost = throttler.RouteCosts(map[string]int{
+//		"GET/search":       5,
+//		"/export/{format}": 20,
+//	}, 1)
+func RouteCosts(costs map[string]int, defaultCost int) CostFunc {
+	return func(ctx iris.Context) int {
+		route := ctx.GetCurrentRoute()
+		if route == nil {
+			return defaultCost
+		}
+
+		if cost, ok := costs[route.Name()]; ok {
+			return cost
+		}
+
+		if cost, ok := costs[route.Path()]; ok {
+			return cost
+		}
+
+		return defaultCost
+	}
+}
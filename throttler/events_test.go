@@ -0,0 +1,62 @@
+package throttler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+)
+
+type recordedDecision struct {
+	route, policy, key, decision string
+}
+
+type decisionRecorder []recordedDecision
+
+func (r *decisionRecorder) RecordDecision(route, policy, key, decision string) {
+	*r = append(*r, recordedDecision{route, policy, key, decision})
+}
+
+func TestRecord(t *testing.T) {
+	var recorded decisionRecorder
+	observers := []throttler.Observer{throttler.Record(&recorded)}
+	byAPIKey := func(ctx iris.Context) string { return ctx.GetHeader("X-API-Key") }
+
+	app := iris.New()
+	app.Get("/", (&throttler.RateLimiter{
+		RateLimiter: newTestRateLimiter(t),
+		KeyFunc:     byAPIKey,
+		Observers:   observers,
+	}).RateLimit, func(ctx iris.Context) {})
+	app.Get("/shadow", (&throttler.RateLimiter{
+		RateLimiter:  newTestRateLimiter(t),
+		KeyFunc:      byAPIKey,
+		PolicyName:   "minute",
+		Observers:    observers,
+		Shadow:       true,
+		ShadowDenied: func(iris.Context, throttler.Result, http.Header) {},
+	}).RateLimit, func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/", "/", "/shadow", "/shadow"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", "a")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	expected := decisionRecorder{
+		{"GET/", throttler.DefaultPolicyName, "a", throttler.DecisionAllowed},
+		{"GET/", throttler.DefaultPolicyName, "a", throttler.DecisionDenied},
+		{"GET/shadow", "minute", "a", throttler.DecisionAllowed},
+		{"GET/shadow", "minute", "a", throttler.DecisionShadowDenied},
+	}
+	if !reflect.DeepEqual(recorded, expected) {
+		t.Fatalf("expected decisions %v but got %v", expected, recorded)
+	}
+}
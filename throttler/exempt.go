@@ -0,0 +1,75 @@
+package throttler
+
+import (
+	"net/netip"
+
+	"github.com/kataras/iris/v12"
+)
+
+// Exemption reports whether a request should not be limited, see the RateLimiter.Exempt field.
+type Exemption func(ctx iris.Context) bool
+
+// ExemptIPs returns an Exemption which matches the requests of the IP addresses or CIDR ranges, e.g. "10.0.0.0/8".
+// The client address is the Context.RemoteAddr, which respects the RemoteAddrHeaders of the Iris Configuration,
+// see ExemptKeys with ByIP for trusted proxies.
+//
+// It panics if a range is not a valid IP address or CIDR range.
+func ExemptIPs(ranges ...string) Exemption {
+	prefixes := make([]netip.Prefix, 0, len(ranges))
+	for _, r := range ranges {
+		prefix, err := parsePrefix(r)
+		if err != nil {
+			panic("throttler: invalid exempt IP range: " + err.Error())
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return func(ctx iris.Context) bool {
+		addr, err := netip.ParseAddr(ctx.RemoteAddr())
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ExemptKeys returns an Exemption which matches the requests that the "keyFunc" returns one of the "keys" for,
+// e.g. ExemptKeys(ByUser(), "admin") or ExemptKeys(ByIP("10.0.0.1"), "192.168.1.10").
+func ExemptKeys(keyFunc KeyFunc, keys ...string) Exemption {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+
+	return func(ctx iris.Context) bool {
+		key := keyFunc(ctx)
+		if key == "" {
+			return false
+		}
+
+		_, ok := set[key]
+		return ok
+	}
+}
+
+// ExemptRoutes returns an Exemption which matches the routes of the names (e.g. "GET/health")
+// or path templates (e.g. "/health").
+func ExemptRoutes(routes ...string) Exemption {
+	return func(ctx iris.Context) bool {
+		route := ctx.GetCurrentRoute()
+		if route == nil {
+			return false
+		}
+
+		return contains(routes, route.Name()) || contains(routes, route.Path())
+	}
+}
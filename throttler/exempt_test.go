@@ -0,0 +1,113 @@
+package throttler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+)
+
+func newTestRateLimiter(t *testing.T) throttled.RateLimiterCtx {
+	t.Helper()
+
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, throttled.RateQuota{MaxRate: throttled.PerMin(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rateLimiter
+}
+
+func TestExempt(t *testing.T) {
+	limiter := &throttler.RateLimiter{
+		RateLimiter: newTestRateLimiter(t),
+		Exempt: []throttler.Exemption{
+			throttler.ExemptIPs("10.0.0.0/8"),
+			throttler.ExemptKeys(throttler.ByHeader("X-Admin"), "9f86d081884c7d659a2feaa0c55ad015"),
+			throttler.ExemptRoutes("/health"),
+		},
+	}
+
+	app := iris.New()
+	app.Use(limiter.RateLimit)
+	app.Get("/", func(ctx iris.Context) {})
+	app.Get("/health", func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path, remoteAddr, admin string) int {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if admin != "" {
+			req.Header.Set("X-Admin", admin)
+		}
+		app.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	expectCodes := func(name string, codes ...int) {
+		t.Helper()
+
+		for i, code := range codes {
+			if code != http.StatusOK {
+				t.Errorf("%s [%d]: expected status code %d but got %d", name, i, http.StatusOK, code)
+			}
+		}
+	}
+
+	expectCodes("internal", serve("/", "10.1.2.3:1234", ""), serve("/", "10.1.2.3:1234", ""))
+	expectCodes("admin", serve("/", "192.0.2.1:1234", "test"), serve("/", "192.0.2.1:1234", "test"))
+	expectCodes("health", serve("/health", "192.0.2.1:1234", ""), serve("/health", "192.0.2.1:1234", ""))
+
+	serve("/", "192.0.2.1:1234", "")
+	if code := serve("/", "192.0.2.1:1234", ""); code != http.StatusTooManyRequests {
+		t.Fatalf("expected status code %d but got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	var denied []throttler.Result
+
+	limiter := &throttler.RateLimiter{
+		RateLimiter: newTestRateLimiter(t),
+		Shadow:      true,
+		ShadowDenied: func(ctx iris.Context, result throttler.Result, header http.Header) {
+			if header.Get("Retry-After") == "" {
+				t.Errorf("expected the Retry-After header to be computed")
+			}
+			denied = append(denied, result)
+		},
+	}
+
+	app := iris.New()
+	app.Get("/", limiter.RateLimit, func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("[%d] expected status code %d but got %d", i, http.StatusOK, res.Code)
+		}
+		expectHeader(t, res, "X-RateLimit-Limit", "")
+	}
+
+	if len(denied) != 2 || !denied[0].Limited || denied[0].Policy != throttler.DefaultPolicyName {
+		t.Fatalf("expected two shadow denials but got %v", denied)
+	}
+}
@@ -0,0 +1,205 @@
+package throttler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileBackend is a Backend which keeps its keys in a JSON file.
+// It is a single-host reference implementation, suitable for tests and a few processes
+// of the same host with a small number of keys:
+//   - each operation locks the whole file exclusively and rewrites it,
+//   - the current time is the local clock, so the hosts of a shared volume must not share a file,
+//   - the file is locked on unix systems only, elsewhere it must not be shared between processes.
+//
+// Use the throttler/pgbackend module, or another Backend with a shared clock and per-key
+// compare-and-swap, to share the limits between multiple hosts.
+type FileBackend struct {
+	path string
+
+	mu   sync.Mutex
+	lock *os.File
+}
+
+var _ Backend = (*FileBackend)(nil)
+
+type fileEntry struct {
+	Value     int64 `json:"v"`
+	ExpiresAt int64 `json:"e,omitempty"` // unix nanoseconds.
+}
+
+func (e fileEntry) expired(now time.Time) bool {
+	return e.ExpiresAt > 0 && e.ExpiresAt <= now.UnixNano()
+}
+
+// NewFileBackend returns a new FileBackend which stores its keys in the file of "path".
+// A "path.lock" file is created next to it.
+func NewFileBackend(path string) (*FileBackend, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("throttler: file backend: %w", err)
+	}
+
+	return &FileBackend{path: path, lock: lock}, nil
+}
+
+// Close releases the lock file.
+func (b *FileBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lock.Close()
+}
+
+// Now returns the local time, see the FileBackend's limitations.
+func (b *FileBackend) Now(ctx context.Context) (time.Time, error) {
+	return time.Now(), ctx.Err()
+}
+
+// Get returns the value of the key and true, or false if the key does not exist or it has expired.
+func (b *FileBackend) Get(ctx context.Context, key string) (value int64, ok bool, err error) {
+	err = b.do(ctx, func(entries map[string]fileEntry, now time.Time) bool {
+		if entry, exists := entries[key]; exists && !entry.expired(now) {
+			value, ok = entry.Value, true
+		}
+
+		return false
+	})
+
+	return
+}
+
+// SetIfNotExists sets the value of the key only if it does not exist or it has expired.
+func (b *FileBackend) SetIfNotExists(ctx context.Context, key string, value int64, expiresAt time.Time) (set bool, err error) {
+	err = b.do(ctx, func(entries map[string]fileEntry, now time.Time) bool {
+		if entry, exists := entries[key]; exists && !entry.expired(now) {
+			return false
+		}
+
+		entries[key] = newFileEntry(value, expiresAt)
+		set = true
+		return true
+	})
+
+	return
+}
+
+// CompareAndSwap sets the value of the key to "new" only if its current value is "old".
+func (b *FileBackend) CompareAndSwap(ctx context.Context, key string, old, new int64, expiresAt time.Time) (swapped bool, err error) {
+	err = b.do(ctx, func(entries map[string]fileEntry, now time.Time) bool {
+		entry, exists := entries[key]
+		if !exists || entry.expired(now) || entry.Value != old {
+			return false
+		}
+
+		entries[key] = newFileEntry(new, expiresAt)
+		swapped = true
+		return true
+	})
+
+	return
+}
+
+func newFileEntry(value int64, expiresAt time.Time) fileEntry {
+	entry := fileEntry{Value: value}
+	if !expiresAt.IsZero() {
+		entry.ExpiresAt = expiresAt.UnixNano()
+	}
+
+	return entry
+}
+
+// do locks the file, reads its entries and calls "fn".
+// If "fn" returns true, the entries are written back to the file, without the expired ones.
+func (b *FileBackend) do(ctx context.Context, fn func(entries map[string]fileEntry, now time.Time) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := lockFile(b.lock); err != nil {
+		return fmt.Errorf("throttler: file backend: lock: %w", err)
+	}
+	defer unlockFile(b.lock)
+
+	entries, err := b.read()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !fn(entries, now) {
+		return nil
+	}
+
+	for key, entry := range entries {
+		if entry.expired(now) {
+			delete(entries, key)
+		}
+	}
+
+	return b.write(entries)
+}
+
+func (b *FileBackend) read() (map[string]fileEntry, error) {
+	entries := make(map[string]fileEntry)
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return entries, nil
+		}
+
+		return nil, fmt.Errorf("throttler: file backend: %w", err)
+	}
+
+	if len(data) == 0 {
+		return entries, nil
+	}
+
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("throttler: file backend: %s: %w", b.path, err)
+	}
+
+	return entries, nil
+}
+
+// write replaces the file with a new one, so readers never see a partially written file.
+func (b *FileBackend) write(entries map[string]fileEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("throttler: file backend: %w", err)
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("throttler: file backend: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("throttler: file backend: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), b.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("throttler: file backend: %w", err)
+	}
+
+	return nil
+}
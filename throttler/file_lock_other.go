@@ -0,0 +1,16 @@
+//go:build !unix
+
+package throttler
+
+import "os"
+
+// On systems without flock(2) the FileBackend is safe
+// for concurrent use by a single process only.
+
+func lockFile(*os.File) error {
+	return nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
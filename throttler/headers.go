@@ -0,0 +1,108 @@
+package throttler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderFormat selects the rate limit headers written to the responses, see the RateLimiter.Headers field.
+type HeaderFormat uint8
+
+const (
+	// LegacyHeaders writes the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers.
+	LegacyHeaders HeaderFormat = 1 << iota
+	// IETFHeaders writes the RateLimit-Policy and RateLimit headers of the
+	// IETF "RateLimit header fields for HTTP" draft, e.g.
+	//
+	//	RateLimit-Policy: "second";q=10;w=1, "hour";q=1000;w=3600
+	//	RateLimit: "second";r=4;t=1
+	IETFHeaders
+)
+
+const (
+	rateLimitPolicyHeader = "RateLimit-Policy"
+	rateLimitHeader       = "RateLimit"
+
+	// DefaultPolicyName is the policy name of a RateLimiter which does not enforce named limits.
+	DefaultPolicyName = "default"
+)
+
+func (f HeaderFormat) has(format HeaderFormat) bool {
+	if f == 0 { // the zero value writes the legacy headers only, for backwards compatibility.
+		f = LegacyHeaders
+	}
+
+	return f&format != 0
+}
+
+// setIETFHeaders writes the RateLimit-Policy and RateLimit headers.
+// The "t" parameter is the number of seconds until the next request is permitted when the request is limited,
+// or until the quota is fully restored otherwise.
+func setIETFHeaders(header http.Header, result Result, policyHeader string) {
+	if policyHeader == "" && result.Limit >= 0 {
+		policyHeader = quoteHeaderString(result.Policy) + ";q=" + strconv.Itoa(result.Limit)
+	}
+
+	if policyHeader != "" {
+		header.Set(rateLimitPolicyHeader, policyHeader)
+	}
+
+	reset := result.ResetAfter
+	if result.Limited && result.RetryAfter >= 0 {
+		reset = result.RetryAfter
+	}
+
+	value := quoteHeaderString(result.Policy)
+	if result.Remaining >= 0 {
+		value += ";r=" + strconv.Itoa(result.Remaining)
+	}
+	if reset >= 0 {
+		value += ";t=" + strconv.Itoa(ceilSeconds(reset))
+	}
+
+	header.Set(rateLimitHeader, value)
+}
+
+// renderPolicyHeader returns the RateLimit-Policy header value of "limits".
+func renderPolicyHeader(limits []Limit) string {
+	items := make([]string, 0, len(limits))
+	for _, limit := range limits {
+		quota := limit.Quota.MaxBurst + 1
+		item := quoteHeaderString(limit.Name) + ";q=" + strconv.Itoa(quota)
+		if limit.Window > 0 {
+			item += ";w=" + strconv.Itoa(ceilSeconds(limit.Window))
+		}
+
+		items = append(items, item)
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// quoteHeaderString returns "s" as a structured field string (RFC 8941).
+func quoteHeaderString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c > 0x7e {
+			continue
+		}
+
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,135 @@
+package throttler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+)
+
+func TestHeaders(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	multi, err := throttler.NewMultiRateLimiter(store,
+		throttler.Limit{Name: "second", Quota: throttled.RateQuota{MaxRate: throttled.PerSec(10), MaxBurst: 9}, Window: time.Second},
+		throttler.Limit{Name: "hour", Quota: throttled.RateQuota{MaxRate: throttled.PerHour(2), MaxBurst: 1}, Window: time.Hour},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	single, err := throttled.NewGCRARateLimiterCtx(store, throttled.RateQuota{MaxRate: throttled.PerMin(1), MaxBurst: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := iris.New()
+	app.Get("/multi", (&throttler.RateLimiter{RateLimiter: multi, Headers: throttler.IETFHeaders}).RateLimit)
+	app.Get("/single", (&throttler.RateLimiter{RateLimiter: single, Headers: throttler.LegacyHeaders | throttler.IETFHeaders, PolicyName: "api"}).RateLimit)
+	app.Get("/legacy", (&throttler.RateLimiter{RateLimiter: single, VaryBy: &throttled.VaryBy{Path: true}}).RateLimit)
+	if err = app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("/multi")
+	expectHeader(t, res, "RateLimit-Policy", `"second";q=10;w=1, "hour";q=2;w=3600`)
+	expectHeader(t, res, "RateLimit", `"hour";r=1;t=1800`)
+	expectHeader(t, res, "X-RateLimit-Limit", "")
+
+	serve("/multi")
+	res = serve("/multi")
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status code %d but got %d", http.StatusTooManyRequests, res.Code)
+	}
+	expectHeader(t, res, "RateLimit", `"hour";r=0;t=1800`)
+	expectHeader(t, res, "Retry-After", "1800")
+	expectHeader(t, res, "Content-Type", "application/problem+json; charset=utf-8")
+
+	var problem map[string]any
+	if err = json.Unmarshal(res.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem["policy"] != "hour" || problem["retryAfter"] != float64(1800) || problem["status"] != float64(429) {
+		t.Errorf("unexpected problem body: %s", res.Body.String())
+	}
+
+	res = serve("/single")
+	expectHeader(t, res, "RateLimit-Policy", `"api";q=5`)
+	expectHeader(t, res, "RateLimit", `"api";r=4;t=60`)
+	expectHeader(t, res, "X-RateLimit-Limit", "5")
+
+	res = serve("/legacy")
+	expectHeader(t, res, "X-RateLimit-Remaining", "4")
+	expectHeader(t, res, "RateLimit", "")
+}
+
+func TestHeadersChainedLimiters(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	global, err := throttled.NewGCRARateLimiterCtx(store, throttled.RateQuota{MaxRate: throttled.PerMin(100), MaxBurst: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routeStore, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	route, err := throttled.NewGCRARateLimiterCtx(routeStore, throttled.RateQuota{MaxRate: throttled.PerMin(1), MaxBurst: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := throttler.LegacyHeaders | throttler.IETFHeaders
+	app := iris.New()
+	app.Get("/",
+		(&throttler.RateLimiter{RateLimiter: global, Headers: headers, PolicyName: "global"}).RateLimit,
+		(&throttler.RateLimiter{RateLimiter: route, Headers: headers, PolicyName: "route"}).RateLimit,
+		func(ctx iris.Context) {},
+	)
+	if err = app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	var res *httptest.ResponseRecorder
+	for range 3 {
+		res = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		app.ServeHTTP(res, req)
+	}
+
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status code %d but got %d", http.StatusTooManyRequests, res.Code)
+	}
+
+	for _, name := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "RateLimit-Policy", "RateLimit"} {
+		if values := res.Header().Values(name); len(values) != 1 {
+			t.Errorf("expected a single %s header but got %q", name, values)
+		}
+	}
+
+	expectHeader(t, res, "X-RateLimit-Limit", "2")
+	expectHeader(t, res, "RateLimit-Policy", `"route";q=2`)
+}
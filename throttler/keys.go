@@ -0,0 +1,221 @@
+package throttler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+)
+
+// KeyFunc returns the rate limiting key of a request.
+// An empty key means that the request cannot be identified,
+// see the RateLimiter.FallbackKey and SkipEmptyKey fields.
+type KeyFunc func(ctx iris.Context) string
+
+// Compose returns a KeyFunc which joins the keys of "keyFuncs",
+// e.g. Compose(ByUser(), ByRoute()) limits each user on each route separately.
+// The composite key is empty when any of the keys is empty.
+func Compose(keyFuncs ...KeyFunc) KeyFunc {
+	return func(ctx iris.Context) string {
+		parts := make([]string, 0, len(keyFuncs))
+		for _, keyFunc := range keyFuncs {
+			part := keyFunc(ctx)
+			if part == "" {
+				return ""
+			}
+
+			parts = append(parts, part)
+		}
+
+		return strings.Join(parts, "|")
+	}
+}
+
+// FirstOf returns a KeyFunc which returns the first non-empty key of "keyFuncs",
+// e.g. FirstOf(ByUser(), ByIP()) limits authenticated users by their ID and anonymous ones by their IP.
+// The keys are prefixed by their position, so the keys of different KeyFuncs never collide.
+func FirstOf(keyFuncs ...KeyFunc) KeyFunc {
+	return func(ctx iris.Context) string {
+		for i, keyFunc := range keyFuncs {
+			if key := keyFunc(ctx); key != "" {
+				return string(rune('a'+i)) + ":" + key
+			}
+		}
+
+		return ""
+	}
+}
+
+// FromVaryBy returns a KeyFunc from a VaryBy, e.g. a throttled.VaryBy.
+func FromVaryBy(v interface{ Key(*http.Request) string }) KeyFunc {
+	return func(ctx iris.Context) string {
+		return v.Key(ctx.Request())
+	}
+}
+
+// ByIP returns a KeyFunc which keys the requests by the client's IP address.
+//
+// The "trustedProxies" are the IP addresses or CIDR ranges of the reverse proxies in front of the server,
+// the X-Forwarded-For header is read only when the request is sent by one of them
+// and the client is its right-most address which is not a trusted proxy.
+// If no trusted proxies are given, the Context.RemoteAddr is used, which respects
+// the RemoteAddrHeaders of the Iris Configuration.
+//
+// It panics if a trusted proxy is not a valid IP address or CIDR range.
+func ByIP(trustedProxies ...string) KeyFunc {
+	if len(trustedProxies) == 0 {
+		return func(ctx iris.Context) string {
+			return ctx.RemoteAddr()
+		}
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			panic("throttler: invalid trusted proxy: " + err.Error())
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(ctx iris.Context) string {
+		r := ctx.Request()
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		peer, err := netip.ParseAddr(host)
+		if err != nil {
+			return host
+		}
+		peer = peer.Unmap()
+
+		if !isTrusted(peer) {
+			return peer.String()
+		}
+
+		var forwarded []string
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			forwarded = append(forwarded, strings.Split(value, ",")...)
+		}
+
+		client := peer
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+			if err != nil {
+				break
+			}
+
+			client = addr.Unmap()
+			if !isTrusted(client) {
+				break
+			}
+		}
+
+		return client.String()
+	}
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ByUser returns a KeyFunc which keys the requests by the ID of the authenticated user (see Context.User).
+// The key is empty for anonymous requests.
+func ByUser() KeyFunc {
+	return func(ctx iris.Context) string {
+		u := ctx.User()
+		if u == nil {
+			return ""
+		}
+
+		id, err := u.GetID()
+		if err != nil {
+			return ""
+		}
+
+		return id
+	}
+}
+
+// ByHeader returns a KeyFunc which keys the requests by the value of a request header, e.g. "X-API-Key".
+// The value is hashed, so secrets are not kept in the store.
+// The key is empty when the header is missing.
+func ByHeader(name string) KeyFunc {
+	return func(ctx iris.Context) string {
+		value := ctx.GetHeader(name)
+		if value == "" {
+			return ""
+		}
+
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:16])
+	}
+}
+
+// ByRoute returns a KeyFunc which keys the requests by the name of the current route,
+// which defaults to its method and path template (e.g. "GET/users/{id:uint64}"),
+// so all requests of a route share the same key whatever their path parameters.
+// The key is empty when no route matched.
+func ByRoute() KeyFunc {
+	return func(ctx iris.Context) string {
+		route := ctx.GetCurrentRoute()
+		if route == nil {
+			return ""
+		}
+
+		return route.Name()
+	}
+}
+
+// ByParam returns a KeyFunc which keys the requests by the value of a path parameter, e.g. a tenant ID.
+// The key is empty when the parameter is missing.
+func ByParam(name string) KeyFunc {
+	return func(ctx iris.Context) string {
+		return ctx.Params().Get(name)
+	}
+}
+
+// BySubdomain returns a KeyFunc which keys the requests by their subdomain, e.g. the tenant of a multi-tenant application.
+// The key is empty when there is no subdomain.
+func BySubdomain() KeyFunc {
+	return func(ctx iris.Context) string {
+		return ctx.Subdomain()
+	}
+}
+
+// ByValue returns a KeyFunc which keys the requests by a context value (see Context.Values),
+// e.g. a tenant ID stored by a previous middleware. The key is empty when the value is missing.
+func ByValue(key string) KeyFunc {
+	return func(ctx iris.Context) string {
+		return ctx.Values().GetString(key)
+	}
+}
@@ -0,0 +1,104 @@
+package throttler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+)
+
+func TestKeyFuncs(t *testing.T) {
+	var keys []string
+	record := func(keyFunc throttler.KeyFunc) iris.Handler {
+		return func(ctx iris.Context) {
+			keys = append(keys, keyFunc(ctx))
+			ctx.Next()
+		}
+	}
+
+	app := iris.New()
+	app.Get("/tenants/{tenant}/users/{id:uint64}",
+		record(throttler.ByIP("10.0.0.0/8")),
+		record(throttler.ByRoute()),
+		record(throttler.ByParam("tenant")),
+		record(throttler.Compose(throttler.ByParam("tenant"), throttler.ByHeader("X-API-Key"))),
+		record(throttler.Compose(throttler.ByParam("tenant"), throttler.ByUser())),
+		record(throttler.FirstOf(throttler.ByUser(), throttler.ByIP())),
+	)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/tenants/acme/users/42", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7, 10.0.0.2")
+	req.Header.Set("X-API-Key", "secret")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := []string{
+		"198.51.100.7",
+		"GET/tenants/{tenant}/users/{id:uint64}",
+		"acme",
+		"acme|2bb80d537b1da3e38bd30361aa855686",
+		"",
+		"b:10.0.0.1",
+	}
+
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %d keys but got %d", len(expected), len(keys))
+	}
+
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("[%d] expected key %q but got %q", i, expected[i], keys[i])
+		}
+	}
+}
+
+func TestFallbackKey(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, throttled.RateQuota{MaxRate: throttled.PerMin(1), MaxBurst: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(limiter *throttler.RateLimiter) []int {
+		app := iris.New()
+		app.Get("/", limiter.RateLimit, func(ctx iris.Context) {})
+		if err := app.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		var codes []int
+		for range 2 {
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			app.ServeHTTP(res, req)
+			codes = append(codes, res.Code)
+		}
+
+		return codes
+	}
+
+	if codes := serve(&throttler.RateLimiter{RateLimiter: rateLimiter, KeyFunc: throttler.ByUser()}); codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("expected anonymous requests to share the default fallback bucket but got %v", codes)
+	}
+
+	if codes := serve(&throttler.RateLimiter{RateLimiter: rateLimiter, KeyFunc: throttler.ByUser(), FallbackKey: "anonymous"}); codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("expected anonymous requests to share the fallback bucket but got %v", codes)
+	}
+
+	if codes := serve(&throttler.RateLimiter{RateLimiter: rateLimiter, KeyFunc: throttler.ByUser(), SkipEmptyKey: true}); codes[1] != http.StatusOK {
+		t.Fatalf("expected anonymous requests to pass with SkipEmptyKey but got %v", codes)
+	}
+}
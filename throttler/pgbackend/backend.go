@@ -0,0 +1,152 @@
+// Package pgbackend provides a throttler.Backend backed by a PostgreSQL database
+// through the github.com/kataras/pg package, the one used by the iris-contrib/middleware/pg middleware.
+// It is a separate module, so the throttler's users do not depend on the PostgreSQL driver.
+//
+//	$ go get github.com/iris-contrib/middleware/throttler/pgbackend@master
+package pgbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/iris-contrib/middleware/throttler"
+
+	"github.com/kataras/pg"
+)
+
+// DefaultTableName is the default rate limiting table name.
+const DefaultTableName = "throttler_keys"
+
+// Options holds the Backend's options.
+type Options struct {
+	// TableName is the rate limiting table name, it may be qualified by a schema, e.g. "app.throttler_keys".
+	// Defaults to DefaultTableName.
+	TableName string
+	// AutoMigrate, if true, creates the rate limiting table if it does not exist.
+	AutoMigrate bool
+}
+
+// Backend is a throttler.Backend backed by a PostgreSQL database.
+// The current time is the database's one, so all the instances of an application share the same clock,
+// and each operation is a single statement which locks only the row of its key.
+// Expired rows are ignored and replaced on write, call the DeleteExpired method periodically to remove them.
+type Backend struct {
+	db        *pg.DB
+	tableName string
+}
+
+var _ throttler.Backend = (*Backend)(nil)
+
+var tableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// New returns a new Backend of the "db" database.
+// The *pg.DB can be retrieved from the pg middleware through its GetDB method.
+//
+// Example:
+//
+//	backend, err := pgbackend.New(db, pgbackend.Options{AutoMigrate: true})
+//	store := throttler.NewStore(backend, "api:")
+func New(db *pg.DB, opts Options) (*Backend, error) {
+	tableName := opts.TableName
+	if tableName == "" {
+		tableName = DefaultTableName
+	}
+
+	if !tableNameRegexp.MatchString(tableName) {
+		return nil, fmt.Errorf("pgbackend: invalid table name: %q", tableName)
+	}
+
+	b := &Backend{
+		db:        db,
+		tableName: tableName,
+	}
+
+	if opts.AutoMigrate {
+		if err := b.Migrate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// Migrate creates the rate limiting table if it does not exist.
+func (b *Backend) Migrate(ctx context.Context) error {
+	_, err := b.db.Exec(ctx, createTableQuery(b.tableName))
+	return err
+}
+
+func createTableQuery(tableName string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + tableName + ` ("key" text NOT NULL, "value" bigint NOT NULL, ` +
+		`"expires_at" timestamptz, PRIMARY KEY ("key"));`
+}
+
+// notExpired is the condition of the rows which have not expired.
+const notExpired = `(expires_at IS NULL OR expires_at > now())`
+
+// Now returns the current time of the database.
+func (b *Backend) Now(ctx context.Context) (now time.Time, err error) {
+	err = b.db.QueryRow(ctx, `SELECT now();`).Scan(&now)
+	return
+}
+
+// Get returns the value of the key and true, or false if the key does not exist or it has expired.
+func (b *Backend) Get(ctx context.Context, key string) (int64, bool, error) {
+	var value int64
+	query := `SELECT value FROM ` + b.tableName + ` WHERE key = $1 AND ` + notExpired + `;`
+	if err := b.db.QueryRow(ctx, query, key).Scan(&value); err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return 0, false, nil
+		}
+
+		return 0, false, err
+	}
+
+	return value, true, nil
+}
+
+// SetIfNotExists sets the value of the key only if it does not exist or it has expired.
+func (b *Backend) SetIfNotExists(ctx context.Context, key string, value int64, expiresAt time.Time) (bool, error) {
+	query := `INSERT INTO ` + b.tableName + ` AS t (key, value, expires_at) VALUES ($1, $2, $3) ` +
+		`ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at ` +
+		`WHERE t.expires_at IS NOT NULL AND t.expires_at <= now();`
+	tag, err := b.db.Exec(ctx, query, key, value, nullTime(expiresAt))
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// CompareAndSwap sets the value of the key to "new" only if its current value is "old".
+func (b *Backend) CompareAndSwap(ctx context.Context, key string, old, new int64, expiresAt time.Time) (bool, error) {
+	query := `UPDATE ` + b.tableName + ` SET value = $3, expires_at = $4 WHERE key = $1 AND value = $2 AND ` + notExpired + `;`
+	tag, err := b.db.Exec(ctx, query, key, old, new, nullTime(expiresAt))
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteExpired removes the expired keys and returns their number.
+func (b *Backend) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := b.db.Exec(ctx, `DELETE FROM `+b.tableName+` WHERE expires_at <= now();`)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// nullTime returns nil for a zero time, so keys without an expiration are stored as NULL.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
@@ -0,0 +1,313 @@
+package throttler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/throttled/throttled/v2"
+)
+
+// Limit is a named rate quota, e.g.
+//
+//	{Name: "second", Quota: throttled.RateQuota{MaxRate: throttled.PerSec(10), MaxBurst: 9}, Window: time.Second}
+type Limit struct {
+	// Name separates the state of the limit from the state of the other limits on the same store.
+	Name  string
+	Quota throttled.RateQuota
+	// Window is the time window of the quota, e.g. time.Hour for 1000 requests per hour,
+	// written as the "w" parameter of the RateLimit-Policy header.
+	// It is optional, the header has no "w" parameter when it is zero.
+	Window time.Duration
+}
+
+// MultiRateLimiter is a throttled.RateLimiterCtx which enforces multiple limits at the same time,
+// e.g. 10 requests per second and 1000 requests per hour.
+type MultiRateLimiter struct {
+	store        throttled.GCRAStoreCtx
+	limits       []Limit
+	limiters     []*throttled.GCRARateLimiterCtx
+	policyHeader string
+}
+
+var _ throttled.RateLimiterCtx = (*MultiRateLimiter)(nil)
+
+// NewMultiRateLimiter returns a new MultiRateLimiter of GCRA rate limiters
+// which keep their state on the "store". The limit names must be unique.
+func NewMultiRateLimiter(store throttled.GCRAStoreCtx, limits ...Limit) (*MultiRateLimiter, error) {
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("throttler: no limits")
+	}
+
+	m := &MultiRateLimiter{
+		store:    store,
+		limits:   limits,
+		limiters: make([]*throttled.GCRARateLimiterCtx, 0, len(limits)),
+	}
+
+	names := make(map[string]struct{}, len(limits))
+	for _, limit := range limits {
+		if _, exists := names[limit.Name]; exists {
+			return nil, fmt.Errorf("throttler: duplicate limit name %q", limit.Name)
+		}
+		names[limit.Name] = struct{}{}
+
+		limiter, err := throttled.NewGCRARateLimiterCtx(store, limit.Quota)
+		if err != nil {
+			return nil, fmt.Errorf("throttler: limit %q: %w", limit.Name, err)
+		}
+
+		m.limiters = append(m.limiters, limiter)
+	}
+
+	m.policyHeader = renderPolicyHeader(limits)
+	return m, nil
+}
+
+// Limits returns the limits of the rate limiter.
+func (m *MultiRateLimiter) Limits() []Limit {
+	return m.limits
+}
+
+// RateLimitCtx checks all the limits before charging them, so a request denied by a limit
+// is not counted by the others. Concurrent requests of the same key may still exceed a limit
+// between the check and the charge, the limits charged before it then count the denied request.
+// The result is the one of the exceeded limit, or the one with the fewest remaining requests.
+func (m *MultiRateLimiter) RateLimitCtx(ctx context.Context, key string, quantity int) (bool, throttled.RateLimitResult, error) {
+	limited, result, _, err := m.rateLimit(ctx, key, quantity)
+	return limited, result, err
+}
+
+// rateLimit is like RateLimitCtx but it also returns the index of the limit the result refers to.
+func (m *MultiRateLimiter) rateLimit(ctx context.Context, key string, quantity int) (bool, throttled.RateLimitResult, int, error) {
+	var (
+		result  throttled.RateLimitResult
+		index   int
+		charged = -1
+	)
+
+	if len(m.limiters) > 1 && quantity > 0 {
+		for i := range m.limiters {
+			exceeds, err := m.exceeds(ctx, i, key, quantity)
+			if err != nil {
+				return false, result, i, err
+			}
+
+			if exceeds {
+				// a denied request does not modify the limit's state.
+				limited, r, err := m.limiters[i].RateLimitCtx(ctx, m.limits[i].Name+":"+key, quantity)
+				if limited || err != nil {
+					return limited, r, i, err
+				}
+
+				// restored meanwhile, the request is counted.
+				charged, result, index = i, r, i
+				break
+			}
+		}
+	}
+
+	for i, limiter := range m.limiters {
+		r := result
+		if i != charged {
+			limited, lr, err := limiter.RateLimitCtx(ctx, m.limits[i].Name+":"+key, quantity)
+			if err != nil {
+				return false, lr, i, err
+			}
+
+			if limited {
+				return true, lr, i, nil
+			}
+
+			r = lr
+		}
+
+		if index == i || r.Remaining < result.Remaining || (r.Remaining == result.Remaining && r.ResetAfter > result.ResetAfter) {
+			result, index = r, i
+		}
+	}
+
+	return false, result, index, nil
+}
+
+// exceeds reports whether the "quantity" exceeds the remaining requests of the i-th limit, without charging it.
+func (m *MultiRateLimiter) exceeds(ctx context.Context, i int, key string, quantity int) (bool, error) {
+	limit := m.limits[i]
+	key = limit.Name + ":" + key
+
+	tat, now, err := m.store.GetWithTime(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	// the quota is fully restored. A zero quantity peek would store
+	// the state of the key without a TTL, so it is skipped.
+	if tat == -1 || tat <= now.UnixNano() {
+		return quantity > limit.Quota.MaxBurst+1, nil
+	}
+
+	_, r, err := m.limiters[i].RateLimitCtx(ctx, key, 0)
+	if err != nil {
+		return false, err
+	}
+
+	return r.Remaining < quantity, nil
+}
+
+// Rule matches requests to the limits enforced on them.
+type Rule struct {
+	// Name separates the state of the rule from the state of the other rules on the same store.
+	// If it is empty, the rule's index is used.
+	Name string
+	// Methods is a list of HTTP methods the rule matches. If it is empty, the rule matches all methods.
+	Methods []string
+	// Routes is a list of route names (e.g. "GET/users/{id:uint64}" or a custom name) or
+	// path templates (e.g. "/users/{id:uint64}") the rule matches.
+	// A path template which ends with "*" matches all route paths with that prefix.
+	// If it is empty, the rule matches all routes.
+	Routes []string
+	// Plans is a list of plans the rule matches, e.g. "free" or "pro", see NewPolicy.
+	// If it is empty, the rule matches all plans.
+	Plans []string
+	// Limits are the limits enforced on the requests the rule matches.
+	// If it is empty, the requests are not limited.
+	Limits []Limit
+}
+
+func (r *Rule) match(ctx iris.Context, plan string) bool {
+	if len(r.Methods) > 0 && !containsFold(r.Methods, ctx.Method()) {
+		return false
+	}
+
+	if len(r.Plans) > 0 && !contains(r.Plans, plan) {
+		return false
+	}
+
+	if len(r.Routes) > 0 {
+		route := ctx.GetCurrentRoute()
+		if route == nil {
+			return false
+		}
+
+		name, path := route.Name(), route.Path()
+		for _, pattern := range r.Routes {
+			if pattern == name || pattern == path {
+				return true
+			}
+
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Policy selects the limits of each request by its route, HTTP method and plan.
+// Set it to the RateLimiter.Policy field.
+type Policy struct {
+	plan     KeyFunc
+	rules    []Rule
+	limiters []*MultiRateLimiter
+}
+
+// NewPolicy returns a new Policy of "rules" which keep their state on the "store".
+// The "plan" resolves the plan of a request, e.g. from the authenticated user, it can be nil.
+// The first rule which matches a request applies, requests which match no rule are not limited.
+//
+// Usage:
+//
+//	policy, err := throttler.NewPolicy(store, planOfUser,
+//		throttler.Rule{Name: "upload", Routes: []string{"/upload"}, Limits: []throttler.Limit{
+//			{Name: "hour", Quota: throttled.RateQuota{MaxRate: throttled.PerHour(10)}},
+//		}},
+//		throttler.Rule{Name: "pro", Plans: []string{"pro", "enterprise"}, Limits: []throttler.Limit{
+//			{Name: "second", Quota: throttled.RateQuota{MaxRate: throttled.PerSec(100), MaxBurst: 50}},
+//		}},
+//		throttler.Rule{Name: "free", Limits: []throttler.Limit{
+//			{Name: "second", Quota: throttled.RateQuota{MaxRate: throttled.PerSec(10), MaxBurst: 9}, Window: time.Second},
+//			{Name: "hour", Quota: throttled.RateQuota{MaxRate: throttled.PerHour(1000), MaxBurst: 999}, Window: time.Hour},
+//		}},
+//	)
+func NewPolicy(store throttled.GCRAStoreCtx, plan KeyFunc, rules ...Rule) (*Policy, error) {
+	p := &Policy{
+		plan:     plan,
+		rules:    rules,
+		limiters: make([]*MultiRateLimiter, len(rules)),
+	}
+
+	names := make(map[string]struct{}, len(rules))
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule%d", i)
+		}
+
+		if _, exists := names[name]; exists {
+			return nil, fmt.Errorf("throttler: duplicate rule name %q", name)
+		}
+		names[name] = struct{}{}
+
+		if len(rule.Limits) == 0 {
+			continue
+		}
+
+		limits := make([]Limit, len(rule.Limits))
+		for j, limit := range rule.Limits {
+			limit.Name = name + ":" + limit.Name
+			limits[j] = limit
+		}
+
+		limiter, err := NewMultiRateLimiter(store, limits...)
+		if err != nil {
+			return nil, fmt.Errorf("throttler: rule %q: %w", name, err)
+		}
+
+		p.limiters[i] = limiter
+	}
+
+	return p, nil
+}
+
+// RateLimiter returns the rate limiter of the rule which matches the request,
+// or nil if the request should not be limited.
+func (p *Policy) RateLimiter(ctx iris.Context) *MultiRateLimiter {
+	var plan string
+	if p.plan != nil {
+		plan = p.plan(ctx)
+	}
+
+	for i := range p.rules {
+		if p.rules[i].match(ctx, plan) {
+			return p.limiters[i]
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+package throttler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+)
+
+func TestPolicy(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := func(ctx iris.Context) string {
+		return ctx.GetHeader("X-Plan")
+	}
+
+	policy, err := throttler.NewPolicy(store, plan,
+		throttler.Rule{Name: "health", Routes: []string{"/health"}},
+		throttler.Rule{Name: "upload", Methods: []string{"POST"}, Routes: []string{"/files/*"}, Limits: []throttler.Limit{
+			{Name: "hour", Quota: throttled.RateQuota{MaxRate: throttled.PerHour(1)}},
+		}},
+		throttler.Rule{Name: "pro", Plans: []string{"pro"}, Limits: []throttler.Limit{
+			{Name: "second", Quota: throttled.RateQuota{MaxRate: throttled.PerSec(100), MaxBurst: 9}},
+		}},
+		throttler.Rule{Name: "free", Limits: []throttler.Limit{
+			{Name: "second", Quota: throttled.RateQuota{MaxRate: throttled.PerSec(1), MaxBurst: 4}},
+			{Name: "hour", Quota: throttled.RateQuota{MaxRate: throttled.PerHour(3), MaxBurst: 2}},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := &throttler.RateLimiter{Policy: policy}
+
+	app := iris.New()
+	app.Use(limiter.RateLimit)
+	app.Get("/health", func(ctx iris.Context) {})
+	app.Get("/files/{name}", func(ctx iris.Context) {})
+	app.Post("/files/{name}", func(ctx iris.Context) {})
+	if err = app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method, path, plan string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-Plan", plan)
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	for range 5 {
+		if res := serve("GET", "/health", ""); res.Code != http.StatusOK || res.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("expected unlimited health checks")
+		}
+	}
+
+	res := serve("GET", "/files/a", "free")
+	expectHeader(t, res, "X-RateLimit-Limit", "3")
+	expectHeader(t, res, "X-RateLimit-Remaining", "2")
+
+	serve("GET", "/files/a", "free")
+	serve("GET", "/files/a", "free")
+	if res = serve("GET", "/files/a", "free"); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the hourly limit of the free plan to be exceeded but got %d", res.Code)
+	}
+
+	for i := range 10 {
+		if res = serve("GET", "/files/a", "pro"); res.Code != http.StatusOK {
+			t.Fatalf("[%d] expected the pro plan to be allowed but got %d", i, res.Code)
+		}
+	}
+	expectHeader(t, res, "X-RateLimit-Limit", "10")
+
+	serve("POST", "/files/a", "pro")
+	if res = serve("POST", "/files/b", "pro"); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the upload limit to apply to all plans but got %d", res.Code)
+	}
+}
+
+func TestMultiRateLimiterDeniedNotCharged(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minute := throttled.RateQuota{MaxRate: throttled.PerMin(10), MaxBurst: 9}
+	multi, err := throttler.NewMultiRateLimiter(store,
+		throttler.Limit{Name: "minute", Quota: minute},
+		throttler.Limit{Name: "hour", Quota: throttled.RateQuota{MaxRate: throttled.PerHour(2), MaxBurst: 1}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i, expected := range []bool{false, false, true, true, true} {
+		limited, _, err := multi.RateLimitCtx(ctx, "alice", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if limited != expected {
+			t.Fatalf("[%d] expected limited to be %v", i, expected)
+		}
+	}
+
+	peek, err := throttled.NewGCRARateLimiterCtx(store, minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, result, err := peek.RateLimitCtx(ctx, "minute:alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Remaining != 8 {
+		t.Fatalf("expected the denied requests not to be charged to the first limit, remaining: %d", result.Remaining)
+	}
+
+	// quantities larger than a quota are denied before charging the other limits.
+	if limited, _, err := multi.RateLimitCtx(ctx, "bob", 5); err != nil || !limited {
+		t.Fatalf("expected a quantity larger than the hourly quota to be limited: %v", err)
+	}
+
+	if _, result, _ = peek.RateLimitCtx(ctx, "minute:bob", 0); result.Remaining != 10 {
+		t.Fatalf("expected the denied request not to be charged to the first limit, remaining: %d", result.Remaining)
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	store, err := memstore.NewCtx(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = throttler.NewPolicy(store, nil, throttler.Rule{Name: "a"}, throttler.Rule{Name: "a"}); err == nil {
+		t.Errorf("expected an error for duplicate rule names")
+	}
+
+	if _, err = throttler.NewPolicy(store, nil, throttler.Rule{Limits: []throttler.Limit{{Name: "a"}}}); err == nil {
+		t.Errorf("expected an error for an invalid quota")
+	}
+}
+
+func expectHeader(t *testing.T, res *httptest.ResponseRecorder, name, value string) {
+	t.Helper()
+
+	if got := res.Header().Get(name); got != value {
+		t.Errorf("expected %s header to be %q but got %q", name, value, got)
+	}
+}
@@ -0,0 +1,102 @@
+package throttler
+
+import (
+	"context"
+	"time"
+
+	"github.com/throttled/throttled/v2"
+)
+
+// Backend is the storage of a Store. It holds integer values which may expire
+// and it is shared between all the instances of an application,
+// e.g. a database table, see the github.com/iris-contrib/middleware/throttler/pgbackend module.
+//
+// All methods must be safe for concurrent use by multiple goroutines and processes.
+// See the storetest subpackage for its conformance tests.
+type Backend interface {
+	// Now returns the current time of the backend. The GCRA rate limiter
+	// expects all instances which share a backend to share the same clock too.
+	Now(ctx context.Context) (time.Time, error)
+	// Get returns the value of the key and true, or false if the key does not exist or it has expired.
+	Get(ctx context.Context, key string) (int64, bool, error)
+	// SetIfNotExists sets the value of the key only if it does not exist or it has expired
+	// and reports whether the value was set. A zero expiresAt means that the key never expires.
+	SetIfNotExists(ctx context.Context, key string, value int64, expiresAt time.Time) (bool, error)
+	// CompareAndSwap atomically sets the value of the key to "new" only if its current value is "old"
+	// and reports whether the value was set. It returns false with no error if the key does not exist or it has expired.
+	// A zero expiresAt means that the key never expires.
+	CompareAndSwap(ctx context.Context, key string, old, new int64, expiresAt time.Time) (bool, error)
+}
+
+// Store is a throttled.GCRAStoreCtx which keeps the rate limiting state on a Backend,
+// so the limits are enforced across all the instances of an application.
+type Store struct {
+	backend Backend
+	prefix  string
+}
+
+var _ throttled.GCRAStoreCtx = (*Store)(nil)
+
+// NewStore returns a new GCRA store on top of the "backend".
+// The "prefix" is prepended to all keys, so multiple rate limiters can share the same backend.
+//
+// Usage:
+//
+//	backend, err := pgbackend.New(db, pgbackend.Options{AutoMigrate: true})
+//	store := throttler.NewStore(backend, "api:")
+//	rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, quota)
+func NewStore(backend Backend, prefix string) *Store {
+	return &Store{backend: backend, prefix: prefix}
+}
+
+// GetWithTime returns the value of the key or -1 if it does not exist and the current time of the backend.
+func (s *Store) GetWithTime(ctx context.Context, key string) (int64, time.Time, error) {
+	now, err := s.backend.Now(ctx)
+	if err != nil {
+		return 0, now, err
+	}
+
+	value, ok, err := s.backend.Get(ctx, s.prefix+key)
+	if err != nil {
+		return 0, now, err
+	}
+
+	if !ok {
+		return -1, now, nil
+	}
+
+	return value, now, nil
+}
+
+// SetIfNotExistsWithTTL sets the value of the key only if it does not exist.
+func (s *Store) SetIfNotExistsWithTTL(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error) {
+	expiresAt, err := s.expiresAt(ctx, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	return s.backend.SetIfNotExists(ctx, s.prefix+key, value, expiresAt)
+}
+
+// CompareAndSwapWithTTL sets the value of the key to "new" only if its current value is "old".
+func (s *Store) CompareAndSwapWithTTL(ctx context.Context, key string, old, new int64, ttl time.Duration) (bool, error) {
+	expiresAt, err := s.expiresAt(ctx, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	return s.backend.CompareAndSwap(ctx, s.prefix+key, old, new, expiresAt)
+}
+
+func (s *Store) expiresAt(ctx context.Context, ttl time.Duration) (time.Time, error) {
+	if ttl <= 0 {
+		return time.Time{}, nil
+	}
+
+	now, err := s.backend.Now(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return now.Add(ttl), nil
+}
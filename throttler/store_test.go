@@ -0,0 +1,75 @@
+package throttler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/iris-contrib/middleware/throttler"
+	"github.com/iris-contrib/middleware/throttler/storetest"
+
+	"github.com/throttled/throttled/v2"
+	throttledstoretest "github.com/throttled/throttled/v2/store/storetest"
+)
+
+func newFileBackend(t *testing.T, path string) *throttler.FileBackend {
+	t.Helper()
+
+	backend, err := throttler.NewFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { backend.Close() })
+
+	return backend
+}
+
+func TestFileBackend(t *testing.T) {
+	storetest.TestBackend(t, newFileBackend(t, filepath.Join(t.TempDir(), "limits.json")))
+	storetest.TestBackendExpiration(t, newFileBackend(t, filepath.Join(t.TempDir(), "limits.json")))
+
+	path := filepath.Join(t.TempDir(), "limits.json")
+	storetest.TestBackendConcurrency(t, newFileBackend(t, path), newFileBackend(t, path))
+}
+
+func TestStore(t *testing.T) {
+	throttledstoretest.TestGCRAStoreCtx(t, throttler.NewStore(newFileBackend(t, filepath.Join(t.TempDir(), "limits.json")), "test:"))
+	throttledstoretest.TestGCRAStoreTTLCtx(t, throttler.NewStore(newFileBackend(t, filepath.Join(t.TempDir(), "limits.json")), "test:"))
+}
+
+func TestStoreSharedBetweenInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	quota := throttled.RateQuota{MaxRate: throttled.PerMin(1), MaxBurst: 2}
+
+	newInstance := func() *iris.Application {
+		rateLimiter, err := throttled.NewGCRARateLimiterCtx(throttler.NewStore(newFileBackend(t, path), "test:"), quota)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app := iris.New()
+		app.Use((&throttler.RateLimiter{RateLimiter: rateLimiter}).RateLimit)
+		app.Get("/", func(ctx iris.Context) {})
+		if err = app.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		return app
+	}
+
+	instances := []*iris.Application{newInstance(), newInstance()}
+	expected := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+
+	for i, code := range expected {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		instances[i%len(instances)].ServeHTTP(res, req)
+
+		if res.Code != code {
+			t.Fatalf("[%d] expected status code %d but got %d", i, code, res.Code)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package storetest provides the conformance tests of the throttler.Backend implementations.
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iris-contrib/middleware/throttler"
+)
+
+// TestBackend tests the behavior of a throttler.Backend implementation.
+// The "backend" must be empty.
+func TestBackend(t *testing.T, backend throttler.Backend) {
+	ctx := context.Background()
+
+	if _, ok, err := backend.Get(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatalf("expected Get to fail on a missing key")
+	}
+
+	if swapped, err := backend.CompareAndSwap(ctx, "foo", 1, 2, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if swapped {
+		t.Fatalf("expected CompareAndSwap to fail on a missing key")
+	}
+
+	if set, err := backend.SetIfNotExists(ctx, "foo", 1, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if !set {
+		t.Fatalf("expected SetIfNotExists to succeed on a missing key")
+	}
+
+	if set, err := backend.SetIfNotExists(ctx, "foo", 2, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if set {
+		t.Fatalf("expected SetIfNotExists to fail on an existing key")
+	}
+
+	expectValue(t, backend, "foo", 1)
+
+	if swapped, err := backend.CompareAndSwap(ctx, "foo", 2, 3, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if swapped {
+		t.Fatalf("expected CompareAndSwap to fail on a different old value")
+	}
+
+	if swapped, err := backend.CompareAndSwap(ctx, "foo", 1, 3, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if !swapped {
+		t.Fatalf("expected CompareAndSwap to succeed")
+	}
+
+	expectValue(t, backend, "foo", 3)
+	expectValue(t, backend, "bar", -1)
+}
+
+// TestBackendExpiration tests the expiration of the keys of a throttler.Backend implementation.
+// The "backend" must be empty.
+func TestBackendExpiration(t *testing.T, backend throttler.Backend) {
+	ctx := context.Background()
+	ttl := 100 * time.Millisecond
+
+	now, err := backend.Now(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = backend.SetIfNotExists(ctx, "set", 1, now.Add(ttl)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = backend.SetIfNotExists(ctx, "swap", 1, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = backend.CompareAndSwap(ctx, "swap", 1, 2, now.Add(ttl)); err != nil {
+		t.Fatal(err)
+	}
+
+	expectValue(t, backend, "set", 1)
+	expectValue(t, backend, "swap", 2)
+
+	time.Sleep(ttl + 10*time.Millisecond)
+
+	expectValue(t, backend, "set", -1)
+	expectValue(t, backend, "swap", -1)
+
+	if swapped, err := backend.CompareAndSwap(ctx, "swap", 2, 3, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if swapped {
+		t.Fatalf("expected CompareAndSwap to fail on an expired key")
+	}
+
+	if set, err := backend.SetIfNotExists(ctx, "set", 4, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if !set {
+		t.Fatalf("expected SetIfNotExists to succeed on an expired key")
+	}
+
+	expectValue(t, backend, "set", 4)
+}
+
+// TestBackendConcurrency tests that the CompareAndSwap of a throttler.Backend implementation is atomic,
+// the "backends" may be different instances over the same storage.
+// The storage must be empty.
+func TestBackendConcurrency(t *testing.T, backends ...throttler.Backend) {
+	const increments = 25
+
+	ctx := context.Background()
+	if _, err := backends[0].SetIfNotExists(ctx, "counter", 0, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, backend := range backends {
+		for range 2 {
+			wg.Add(1)
+			go func(backend throttler.Backend) {
+				defer wg.Done()
+
+				for range increments {
+					for {
+						value, _, err := backend.Get(ctx, "counter")
+						if err != nil {
+							t.Error(err)
+							return
+						}
+
+						swapped, err := backend.CompareAndSwap(ctx, "counter", value, value+1, time.Time{})
+						if err != nil {
+							t.Error(err)
+							return
+						}
+
+						if swapped {
+							break
+						}
+					}
+				}
+			}(backend)
+		}
+	}
+	wg.Wait()
+
+	expectValue(t, backends[0], "counter", int64(len(backends)*2*increments))
+}
+
+func expectValue(t *testing.T, backend throttler.Backend, key string, want int64) {
+	t.Helper()
+
+	have, ok, err := backend.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		have = -1
+	}
+
+	if have != want {
+		t.Fatalf("expected Get(%q) to return %d but got %d", key, want, have)
+	}
+}
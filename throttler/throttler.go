@@ -2,7 +2,6 @@ package throttler
 
 import (
 	"errors"
-	"math"
 	"net/http"
 	"strconv"
 
@@ -10,6 +9,8 @@ import (
 	"github.com/kataras/iris/v12/context"
 
 	"github.com/throttled/throttled/v2"
+
+	"github.com/iris-contrib/middleware/ratelimit"
 )
 
 func init() {
@@ -18,9 +19,33 @@ func init() {
 
 var (
 	// DefaultDeniedHandler is the default DeniedHandler for an
-	// RateLimiter. It returns a 429 status code with a generic
-	// message.
+	// RateLimiter. It returns a 429 status code with a JSON problem
+	// (RFC 9457) body which contains the number of seconds until the
+	// next request is permitted and the name of the exceeded policy, e.g.
+	// {"status":429,"title":"Too Many Requests","detail":"limit exceeded","retryAfter":10,"policy":"default"}.
 	DefaultDeniedHandler = func(ctx iris.Context) {
+		problem := iris.NewProblem().Status(http.StatusTooManyRequests).Detail("limit exceeded")
+		if result, ok := GetResult(ctx); ok {
+			if result.RetryAfter >= 0 {
+				problem = problem.Key("retryAfter", ceilSeconds(result.RetryAfter))
+			}
+			problem = problem.Key("policy", result.Policy)
+		}
+
+		ctx.StopExecution()
+		ctx.Problem(problem)
+	}
+
+	// DefaultShadowDenied is the default ShadowDenied function for an RateLimiter.
+	// It logs the request which would be denied as a warning through the application's logger.
+	DefaultShadowDenied = func(ctx iris.Context, result Result, header http.Header) {
+		ctx.Application().Logger().Warnf("throttler: shadow: %s %s would be denied by the %q policy (key: %q, retry after: %s)",
+			ctx.Method(), ctx.Path(), result.Policy, result.Key, header.Get("Retry-After"))
+	}
+
+	// TextDeniedHandler is a DeniedHandler which returns a 429
+	// status code with a generic text message.
+	TextDeniedHandler = func(ctx iris.Context) {
 		ctx.StopWithText(http.StatusTooManyRequests, "limit exceeded")
 	}
 
@@ -31,6 +56,10 @@ var (
 	}
 )
 
+// DefaultFallbackKey is the default key of the global bucket of the requests
+// without a key, see the RateLimiter.FallbackKey field.
+const DefaultFallbackKey = "fallback"
+
 // RateLimiter faciliates using a Limiter to limit HTTP requests.
 type RateLimiter struct {
 	// DeniedHandler is called if the request is disallowed. If it is
@@ -42,14 +71,88 @@ type RateLimiter struct {
 	Error func(ctx iris.Context, err error)
 
 	// Limiter is call for each request to determine whether the
-	// request is permitted and update internal state. It must be set.
+	// request is permitted and update internal state. It must be set,
+	// unless the Policy field is set.
 	RateLimiter throttled.RateLimiterCtx
 
+	// Policy selects the limits of each request by its route, HTTP method
+	// and plan, see NewPolicy. It takes precedence over the RateLimiter field.
+	Policy *Policy
+
 	// VaryBy is called for each request to generate a key for the
 	// limiter. If it is nil, all requests use an empty string key.
 	VaryBy interface {
 		Key(*http.Request) string
 	}
+
+	// KeyFunc is called for each request to generate a key for the
+	// limiter, see the ByIP, ByUser, ByHeader, ByRoute and Compose functions.
+	// It takes precedence over the VaryBy field.
+	KeyFunc KeyFunc
+
+	// FallbackKey is the key of the global bucket which limits the requests
+	// that the KeyFunc returns an empty key for, e.g. anonymous requests on ByUser.
+	// If it is empty, the DefaultFallbackKey is used.
+	FallbackKey string
+
+	// SkipEmptyKey, if true, does not limit the requests that the KeyFunc
+	// returns an empty key for, instead of limiting them by the FallbackKey.
+	SkipEmptyKey bool
+
+	// Cost is called for each request to determine the quantity it
+	// counts against the limits, e.g. RouteCosts for expensive search
+	// and export routes. A cost of zero checks the limits without counting
+	// the request. If it is nil, each request costs 1.
+	// Note that a quota with a zero MaxBurst never permits a cost greater than 1.
+	Cost CostFunc
+
+	// Exempt is a list of exemptions, a request which any of them
+	// matches is not limited, e.g. ExemptIPs("10.0.0.0/8") for internal
+	// networks or ExemptRoutes("/health") for health checks.
+	Exempt []Exemption
+
+	// Shadow, if true, computes the limits but it never denies a request
+	// and does not write the rate limit headers, so new limits can be rolled
+	// out safely. The requests which would be denied are passed to the ShadowDenied.
+	Shadow bool
+
+	// ShadowDenied is called, in Shadow mode, for each request which would be denied,
+	// with the headers which would be written. If it is nil, the DefaultShadowDenied
+	// variable is used.
+	ShadowDenied func(ctx iris.Context, result Result, header http.Header)
+
+	// Observers are notified of each rate limiting decision, see Record.
+	Observers []Observer
+
+	// Headers selects the rate limit headers written to the responses,
+	// e.g. LegacyHeaders|IETFHeaders. The Retry-After header is always
+	// written to limited responses. Default is LegacyHeaders.
+	Headers HeaderFormat
+
+	// PolicyName is the policy name reported by the IETF headers and the
+	// DefaultDeniedHandler when the rate limiter does not enforce named limits
+	// (see MultiRateLimiter). Default is DefaultPolicyName.
+	PolicyName string
+}
+
+// Result is the rate limiting result of a request.
+type Result struct {
+	throttled.RateLimitResult
+	// Limited reports whether the request was denied.
+	Limited bool
+	// Policy is the name of the limit the result refers to, the most restrictive one.
+	Policy string
+	// Key is the rate limiting key of the request.
+	Key string
+}
+
+const resultContextKey = "iris.throttler.result"
+
+// GetResult returns the rate limiting result of the current request,
+// e.g. inside a DeniedHandler.
+func GetResult(ctx iris.Context) (Result, bool) {
+	result, ok := ctx.Values().Get(resultContextKey).(Result)
+	return result, ok
 }
 
 // RateLimit is an Iris middleware that limits incoming requests.
@@ -57,24 +160,91 @@ type RateLimiter struct {
 // unchanged.  Limited requests will be passed to the DeniedHandler.
 // X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset and
 // Retry-After headers will be written to the response based on the
-// values in the RateLimitResult.
+// values in the RateLimitResult, see the Headers field for the IETF
+// RateLimit-Policy and RateLimit headers.
 func (t *RateLimiter) RateLimit(ctx iris.Context) {
-	if t.RateLimiter == nil {
+	for _, exempt := range t.Exempt {
+		if exempt(ctx) {
+			ctx.Next()
+			return
+		}
+	}
+
+	rateLimiter := t.RateLimiter
+	if t.Policy != nil {
+		limiter := t.Policy.RateLimiter(ctx)
+		if limiter == nil {
+			ctx.Next()
+			return
+		}
+
+		rateLimiter = limiter
+	}
+
+	if rateLimiter == nil {
 		t.error(ctx, errors.New("you must set a RateLimiter on RateLimiter"))
+		return
 	}
 
-	var k string
-	if t.VaryBy != nil {
-		k = t.VaryBy.Key(ctx.Request())
+	k, ok := t.key(ctx)
+	if !ok {
+		ctx.Next()
+		return
+	}
+
+	quantity := 1
+	if t.Cost != nil {
+		if quantity = t.Cost(ctx); quantity < 0 {
+			quantity = 0
+		}
+	}
+
+	var (
+		limited      bool
+		context      throttled.RateLimitResult
+		policy       = t.PolicyName
+		policyHeader string
+		err          error
+	)
+
+	if m, ok := rateLimiter.(*MultiRateLimiter); ok {
+		var limit int
+		limited, context, limit, err = m.rateLimit(ctx, k, quantity)
+		policy, policyHeader = m.limits[limit].Name, m.policyHeader
+	} else {
+		limited, context, err = rateLimiter.RateLimitCtx(ctx, k, quantity)
 	}
 
-	limited, context, err := t.RateLimiter.RateLimitCtx(ctx, k, 1)
 	if err != nil {
 		t.error(ctx, err)
 		return
 	}
 
-	setRateLimitHeaders(ctx, context)
+	if policy == "" {
+		policy = DefaultPolicyName
+	}
+
+	result := Result{RateLimitResult: context, Limited: limited, Policy: policy, Key: k}
+	ctx.Values().Set(resultContextKey, result)
+	ratelimit.Notify(t.Observers, ctx, Event{Policy: policy, Key: k, Limited: limited, Shadow: t.Shadow})
+
+	if t.Shadow {
+		if limited {
+			header := make(http.Header)
+			t.setHeaders(header, result, policyHeader)
+
+			sd := t.ShadowDenied
+			if sd == nil {
+				sd = DefaultShadowDenied
+			}
+			sd(ctx, result, header)
+		}
+
+		ctx.Next()
+		return
+	}
+
+	t.setHeaders(ctx.ResponseWriter().Header(), result, policyHeader)
 
 	if limited {
 		dh := t.DeniedHandler
@@ -88,6 +258,42 @@ func (t *RateLimiter) RateLimit(ctx iris.Context) {
 	ctx.Next()
 }
 
+func (t *RateLimiter) setHeaders(header http.Header, result Result, policyHeader string) {
+	if t.Headers.has(LegacyHeaders) {
+		setRateLimitHeaders(header, result.RateLimitResult)
+	} else if v := result.RetryAfter; v >= 0 {
+		header.Set("Retry-After", strconv.Itoa(ceilSeconds(v)))
+	}
+
+	if t.Headers.has(IETFHeaders) {
+		setIETFHeaders(header, result, policyHeader)
+	}
+}
+
+func (t *RateLimiter) key(ctx iris.Context) (string, bool) {
+	if t.KeyFunc != nil {
+		if k := t.KeyFunc(ctx); k != "" {
+			return k, true
+		}
+
+		if t.SkipEmptyKey {
+			return "", false
+		}
+
+		if t.FallbackKey != "" {
+			return t.FallbackKey, true
+		}
+
+		return DefaultFallbackKey, true
+	}
+
+	if t.VaryBy != nil {
+		return t.VaryBy.Key(ctx.Request()), true
+	}
+
+	return "", true
+}
+
 func (t *RateLimiter) error(ctx iris.Context, err error) {
 	e := t.Error
 	if e == nil {
@@ -96,22 +302,20 @@ func (t *RateLimiter) error(ctx iris.Context, err error) {
 	e(ctx, err)
 }
 
-func setRateLimitHeaders(ctx iris.Context, context throttled.RateLimitResult) {
+func setRateLimitHeaders(header http.Header, context throttled.RateLimitResult) {
 	if v := context.Limit; v >= 0 {
-		ctx.Header("X-RateLimit-Limit", strconv.Itoa(v))
+		header.Set("X-RateLimit-Limit", strconv.Itoa(v))
 	}
 
 	if v := context.Remaining; v >= 0 {
-		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(v))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(v))
 	}
 
 	if v := context.ResetAfter; v >= 0 {
-		vi := int(math.Ceil(v.Seconds()))
-		ctx.Header("X-RateLimit-Reset", strconv.Itoa(vi))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(v)))
 	}
 
 	if v := context.RetryAfter; v >= 0 {
-		vi := int(math.Ceil(v.Seconds()))
-		ctx.Header("Retry-After", strconv.Itoa(vi))
+		header.Set("Retry-After", strconv.Itoa(ceilSeconds(v)))
 	}
 }
@@ -0,0 +1,37 @@
+package tollboothic
+
+import (
+	"github.com/iris-contrib/middleware/ratelimit"
+)
+
+// Decision labels of an Event.
+const (
+	DecisionAllowed = ratelimit.DecisionAllowed
+	DecisionDenied  = ratelimit.DecisionDenied
+)
+
+type (
+	// Event describes a rate limiting decision, see the Observer interface.
+	// Its Key is the tollbooth keys the request was limited by, joined by "|".
+	Event = ratelimit.Event
+	// Observer is notified of the rate limiting decisions, e.g. a DecisionRecorder (see Record).
+	// Observers are called synchronously and must be safe for concurrent use.
+	Observer = ratelimit.Observer
+	// ObserverFunc is an Observer function.
+	ObserverFunc = ratelimit.ObserverFunc
+	// DecisionRecorder records the rate limiting decisions, e.g. the RateLimitStats
+	// of the iris-contrib/middleware/expmetric package and the RateLimit counters
+	// of the iris-contrib/middleware/prometheus package.
+	DecisionRecorder = ratelimit.DecisionRecorder
+)
+
+// Record returns an Observer which passes the events to the "recorder",
+// the decision is the event's Decision label.
+//
+// Usage:
+//
+//	stats := expmetric.NewRateLimitStats(0)
+//	tollboothic.LimitHandler(limiter, tollboothic.Observers(tollboothic.Record(stats)))
+func Record(recorder DecisionRecorder) Observer {
+	return ratelimit.Record(recorder)
+}
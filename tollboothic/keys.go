@@ -0,0 +1,81 @@
+package tollboothic
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+// KeyPart returns a part of the tollbooth lookup keys of a request, see the KeyParts option.
+// An empty part means that the request cannot be identified and it is not limited,
+// like tollbooth does when the remote IP is unknown.
+type KeyPart func(ctx iris.Context) string
+
+// ByRemoteIP returns a KeyPart of the client's IP address, Context.RemoteAddr,
+// which respects the RemoteAddrHeaders of the Iris Configuration.
+func ByRemoteIP() KeyPart {
+	return func(ctx iris.Context) string {
+		return ctx.RemoteAddr()
+	}
+}
+
+// ByParam returns a KeyPart of a path parameter, e.g. a tenant ID.
+func ByParam(name string) KeyPart {
+	return func(ctx iris.Context) string {
+		return ctx.Params().Get(name)
+	}
+}
+
+// ByUser returns a KeyPart of the ID of the authenticated user, see Context.User.
+func ByUser() KeyPart {
+	return func(ctx iris.Context) string {
+		u := ctx.User()
+		if u == nil {
+			return ""
+		}
+
+		id, err := u.GetID()
+		if err != nil {
+			return ""
+		}
+
+		return id
+	}
+}
+
+// ByValue returns a KeyPart of a context value set by a previous middleware, see Context.Values.
+func ByValue(key string) KeyPart {
+	return func(ctx iris.Context) string {
+		return ctx.Values().GetString(key)
+	}
+}
+
+// ByHeader returns a KeyPart of a request header.
+func ByHeader(name string) KeyPart {
+	return func(ctx iris.Context) string {
+		return ctx.GetHeader(name)
+	}
+}
+
+// ByRoute returns a KeyPart of the current route's name, e.g. "GET/users/{id:uint64}".
+func ByRoute() KeyPart {
+	return func(ctx iris.Context) string {
+		if route := ctx.GetCurrentRoute(); route != nil {
+			return route.Name()
+		}
+
+		return ""
+	}
+}
+
+func buildKeys(ctx iris.Context, parts []KeyPart) ([]string, bool) {
+	keys := make([]string, 0, len(parts))
+	for _, part := range parts {
+		key := part(ctx)
+		if key == "" {
+			return nil, false
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, true
+}
@@ -0,0 +1,84 @@
+package tollboothic
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+// DefaultPolicyName is the default policy name of the LimitHandler's events.
+const DefaultPolicyName = "default"
+
+// Options describes the optional configuration for the LimitHandler.
+type Options struct {
+	// PolicyName is the policy name of the events. Default is DefaultPolicyName.
+	PolicyName string
+	// Observers are notified of each rate limiting decision, see Record.
+	Observers []Observer
+	// KeyParts build the tollbooth lookup keys from the Iris context,
+	// instead of the tollbooth limiter's IP lookups, headers and basic auth users.
+	KeyParts []KeyPart
+	// RejectHandler is called when the limit is exceeded, instead of writing the limiter's message.
+	RejectHandler iris.Handler
+	// FireErrorCode, if true, stops the request with the limiter's status code (429 by default)
+	// without a body, so the application's OnErrorCode handlers fire.
+	// It is ignored when a RejectHandler is set.
+	FireErrorCode bool
+}
+
+// Option sets an optional configuration field of the LimitHandler.
+type Option func(*Options)
+
+// PolicyName sets the policy name of the events, e.g. the name of the limited resource.
+func PolicyName(name string) Option {
+	return func(opts *Options) {
+		opts.PolicyName = name
+	}
+}
+
+// Observers adds observers which are notified of each rate limiting decision.
+func Observers(observers ...Observer) Option {
+	return func(opts *Options) {
+		opts.Observers = append(opts.Observers, observers...)
+	}
+}
+
+// KeyParts builds the tollbooth lookup keys from the Iris context, e.g.
+// KeyParts(ByParam("tenant"), ByUser()) limits each user of each tenant separately.
+// The parts are joined by "|" and a request with an empty part is not limited.
+// The limiter's methods are still respected.
+func KeyParts(parts ...KeyPart) Option {
+	return func(opts *Options) {
+		opts.KeyParts = append(opts.KeyParts, parts...)
+	}
+}
+
+// RejectHandler sets a handler which is called when the limit is exceeded,
+// instead of writing the limiter's message. See GetError.
+func RejectHandler(handler iris.Handler) Option {
+	return func(opts *Options) {
+		opts.RejectHandler = handler
+	}
+}
+
+// FireErrorCode stops the limited requests with the limiter's status code and no body,
+// so the application's OnErrorCode handlers (e.g. app.OnErrorCode(iris.StatusTooManyRequests, ...)) fire.
+func FireErrorCode() Option {
+	return func(opts *Options) {
+		opts.FireErrorCode = true
+	}
+}
+
+func applyOptions(options []Option) (opts Options) {
+	for _, fn := range options {
+		if fn == nil {
+			continue
+		}
+
+		fn(&opts)
+	}
+
+	if opts.PolicyName == "" {
+		opts.PolicyName = DefaultPolicyName
+	}
+
+	return
+}
@@ -0,0 +1,354 @@
+package tollboothic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/didip/tollbooth/v6"
+	"github.com/didip/tollbooth/v6/limiter"
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration which is decoded from a string, e.g. "1h30m".
+type Duration time.Duration
+
+// UnmarshalText decodes a duration string, see time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText encodes the duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LimitConfig describes the configuration of a tollbooth limiter.
+type LimitConfig struct {
+	// Max is the maximum number of requests per second. It must be set.
+	Max float64 `json:"max" yaml:"max"`
+	// Burst is the maximum number of requests in a burst. Default is Max, at least 1.
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"`
+	// Methods is a list of HTTP methods to limit. Default is all methods.
+	Methods []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	// Headers limits only the requests with one of the listed values of these headers,
+	// each value separately. Each header must list at least one value,
+	// tollbooth does not limit the requests of a header without values.
+	Headers map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// BasicAuthUsers is a list of basic authentication users to limit.
+	BasicAuthUsers []string `json:"basic_auth_users,omitempty" yaml:"basic_auth_users,omitempty"`
+	// IPLookups is the list of places to look up the client's IP address,
+	// e.g. ["RemoteAddr"]. Default is ["X-Forwarded-For", "X-Real-IP", "RemoteAddr"].
+	IPLookups []string `json:"ip_lookups,omitempty" yaml:"ip_lookups,omitempty"`
+	// TTL is the expiration of the token buckets, e.g. "1h". Default is 10 years.
+	TTL Duration `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Message is the body of the limited responses. Default is tollbooth's message.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// StatusCode is the status code of the limited responses. Default is 429.
+	StatusCode int `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+}
+
+// NewLimiter returns a new tollbooth limiter of the configuration.
+func (c LimitConfig) NewLimiter() (*limiter.Limiter, error) {
+	if c.Max <= 0 {
+		return nil, fmt.Errorf("max must be greater than zero")
+	}
+
+	for header, entries := range c.Headers {
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("header %q must list at least one value", header)
+		}
+	}
+
+	var expirable *limiter.ExpirableOptions
+	if c.TTL > 0 {
+		expirable = &limiter.ExpirableOptions{DefaultExpirationTTL: time.Duration(c.TTL)}
+	}
+
+	l := tollbooth.NewLimiter(c.Max, expirable)
+
+	if c.Burst > 0 {
+		l.SetBurst(c.Burst)
+	}
+
+	if len(c.Methods) > 0 {
+		l.SetMethods(c.Methods)
+	}
+
+	for header, entries := range c.Headers {
+		l.SetHeader(header, entries)
+	}
+
+	if len(c.BasicAuthUsers) > 0 {
+		l.SetBasicAuthUsers(c.BasicAuthUsers)
+	}
+
+	if len(c.IPLookups) > 0 {
+		l.SetIPLookups(c.IPLookups)
+	}
+
+	if c.Message != "" {
+		l.SetMessage(c.Message)
+	}
+
+	if c.StatusCode > 0 {
+		l.SetStatusCode(c.StatusCode)
+	}
+
+	return l, nil
+}
+
+// Config describes the limits of a Registry.
+//
+// JSON:
+//
+//	{
+//	  "routes": {
+//	    "GET/users/{id:uint64}": {"max": 1, "burst": 5},
+//	    "/api/*": {"max": 10, "ttl": "1h", "methods": ["POST", "PUT"]}
+//	  }
+//	}
+//
+// YAML:
+//
+//	routes:
+//	  GET/users/{id:uint64}:
+//	    max: 1
+//	    burst: 5
+//	  /api/*:
+//	    max: 10
+//	    ttl: 1h
+//	    methods: [POST, PUT]
+type Config struct {
+	// Routes maps route names (e.g. "GET/users/{id:uint64}" or a custom name),
+	// path templates (e.g. "/users/{id:uint64}") or path template prefixes
+	// which end with "*" (e.g. "/api/*") to their limits.
+	// Route names take precedence over path templates and longer prefixes over shorter ones.
+	Routes map[string]LimitConfig `json:"routes" yaml:"routes"`
+}
+
+// LoadConfig reads a Config from a JSON or YAML file, by its extension.
+func LoadConfig(filename string) (Config, error) {
+	var c Config
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return c, err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &c)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &c)
+	default:
+		return c, fmt.Errorf("tollboothic: unsupported config file extension: %q", ext)
+	}
+
+	if err != nil {
+		return c, fmt.Errorf("tollboothic: %s: %w", filename, err)
+	}
+
+	return c, nil
+}
+
+// Registry holds the limiters of the routes of an application, created by a Config.
+// Its limits can be replaced at runtime, see Reload.
+type Registry struct {
+	options []Option
+
+	mu    sync.Mutex // serializes reloads.
+	state atomic.Pointer[registryState]
+}
+
+type registryState struct {
+	config   Config
+	limiters map[string]*limiter.Limiter
+	handlers map[string]iris.Handler
+	prefixes []string // sorted, longest first.
+}
+
+// NewRegistry returns a new Registry of the "config" limits.
+// The "options" apply to the LimitHandler of each limiter,
+// the policy name of its events is its Config.Routes key.
+//
+// Usage:
+//
+//	config, err := tollboothic.LoadConfig("limits.yml")
+//	registry, err := tollboothic.NewRegistry(config)
+//	registry.Attach(app)
+func NewRegistry(config Config, options ...Option) (*Registry, error) {
+	r := &Registry{options: options}
+	if err := r.Reload(config); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload replaces the limits of the registry with the "config" ones.
+// The limiters of unchanged limits are kept, so their clients are not reset.
+// On error, the current limits are kept.
+func (r *Registry) Reload(config Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.state.Load()
+
+	state := &registryState{
+		config:   config,
+		limiters: make(map[string]*limiter.Limiter, len(config.Routes)),
+		handlers: make(map[string]iris.Handler, len(config.Routes)),
+	}
+
+	for pattern, limitConfig := range config.Routes {
+		if current != nil {
+			if prev, ok := current.config.Routes[pattern]; ok && reflect.DeepEqual(prev, limitConfig) {
+				state.limiters[pattern] = current.limiters[pattern]
+				state.handlers[pattern] = current.handlers[pattern]
+				continue
+			}
+		}
+
+		l, err := limitConfig.NewLimiter()
+		if err != nil {
+			return fmt.Errorf("tollboothic: %q: %w", pattern, err)
+		}
+
+		options := append(append([]Option(nil), r.options...), PolicyName(pattern))
+		state.limiters[pattern] = l
+		state.handlers[pattern] = LimitHandler(l, options...)
+	}
+
+	for pattern := range config.Routes {
+		if strings.HasSuffix(pattern, "*") {
+			state.prefixes = append(state.prefixes, pattern)
+		}
+	}
+
+	sort.Slice(state.prefixes, func(i, j int) bool {
+		if len(state.prefixes[i]) != len(state.prefixes[j]) {
+			return len(state.prefixes[i]) > len(state.prefixes[j])
+		}
+		return state.prefixes[i] < state.prefixes[j]
+	})
+
+	r.state.Store(state)
+	return nil
+}
+
+// ReloadFile replaces the limits of the registry with the ones of a JSON or YAML file, see LoadConfig.
+func (r *Registry) ReloadFile(filename string) error {
+	config, err := LoadConfig(filename)
+	if err != nil {
+		return err
+	}
+
+	return r.Reload(config)
+}
+
+// WatchFile reloads the limits of a JSON or YAML file every time it is modified,
+// it checks its modification time every "interval" until the "ctx" is done.
+// Reload errors are passed to "onError", which can be nil.
+func (r *Registry) WatchFile(ctx context.Context, filename string, interval time.Duration, onError func(error)) {
+	var modTime time.Time
+	if info, err := os.Stat(filename); err == nil {
+		modTime = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(filename)
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+
+			if info.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = info.ModTime()
+
+			if err = r.ReloadFile(filename); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Config returns the current configuration of the registry.
+func (r *Registry) Config() Config {
+	return r.state.Load().config
+}
+
+// Limiter returns the limiter of a Config.Routes key, e.g. to modify it at runtime.
+func (r *Registry) Limiter(pattern string) (*limiter.Limiter, bool) {
+	l, ok := r.state.Load().limiters[pattern]
+	return l, ok
+}
+
+// Attach registers the registry's Handler to all the routes of the "app",
+// including the ones which are already registered.
+func (r *Registry) Attach(app *iris.Application) {
+	app.UseGlobal(r.Handler)
+}
+
+// Handler is a middleware which limits the requests of the current route
+// by the limiter of its name, path template or path template prefix.
+// Requests of routes without limits are passed to the next handler.
+func (r *Registry) Handler(ctx iris.Context) {
+	if h := r.state.Load().match(ctx); h != nil {
+		h(ctx)
+		return
+	}
+
+	ctx.Next()
+}
+
+func (s *registryState) match(ctx iris.Context) iris.Handler {
+	route := ctx.GetCurrentRoute()
+	if route == nil {
+		return nil
+	}
+
+	if h, ok := s.handlers[route.Name()]; ok {
+		return h
+	}
+
+	path := route.Path()
+	if h, ok := s.handlers[path]; ok {
+		return h
+	}
+
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(path, strings.TrimSuffix(prefix, "*")) {
+			return s.handlers[prefix]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package tollboothic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestRegistry(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "limits.yml")
+	write := func(content string) {
+		t.Helper()
+
+		if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`
+routes:
+  GET/users/{id:uint64}:
+    max: 1
+    ip_lookups: [RemoteAddr]
+  /api/*:
+    max: 1
+    burst: 2
+    ttl: 1h
+    ip_lookups: [RemoteAddr]
+  /api/admin/*:
+    max: 1
+    methods: [POST]
+    ip_lookups: [RemoteAddr]
+`)
+
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := NewRegistry(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := iris.New()
+	app.Get("/users/{id:uint64}", func(ctx iris.Context) {})
+	app.Get("/api/items", func(ctx iris.Context) {})
+	app.Get("/api/admin/stats", func(ctx iris.Context) {})
+	app.Get("/health", func(ctx iris.Context) {})
+	registry.Attach(app)
+	if err = app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string, n int) (codes []int) {
+		for range n {
+			res := httptest.NewRecorder()
+			app.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+			codes = append(codes, res.Code)
+		}
+
+		return
+	}
+
+	expectCodes := func(path string, codes []int, expected ...int) {
+		t.Helper()
+
+		for i := range expected {
+			if codes[i] != expected[i] {
+				t.Fatalf("%s: expected status codes %v but got %v", path, expected, codes)
+			}
+		}
+	}
+
+	expectCodes("/users/1", serve("/users/1", 2), http.StatusOK, http.StatusTooManyRequests)
+	expectCodes("/api/items", serve("/api/items", 3), http.StatusOK, http.StatusOK, http.StatusTooManyRequests)
+	expectCodes("/api/admin/stats", serve("/api/admin/stats", 3), http.StatusOK, http.StatusOK, http.StatusOK)
+	expectCodes("/health", serve("/health", 3), http.StatusOK, http.StatusOK, http.StatusOK)
+
+	users, _ := registry.Limiter("GET/users/{id:uint64}")
+
+	write(`
+routes:
+  GET/users/{id:uint64}:
+    max: 1
+    ip_lookups: [RemoteAddr]
+  /health:
+    max: 1
+    ip_lookups: [RemoteAddr]
+`)
+	if err = registry.ReloadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if l, _ := registry.Limiter("GET/users/{id:uint64}"); l != users {
+		t.Fatalf("expected the limiter of an unchanged limit to be kept")
+	}
+
+	expectCodes("/users/1", serve("/users/1", 1), http.StatusTooManyRequests)
+	expectCodes("/api/items", serve("/api/items", 3), http.StatusOK, http.StatusOK, http.StatusOK)
+	expectCodes("/health", serve("/health", 2), http.StatusOK, http.StatusTooManyRequests)
+
+	if err = registry.Reload(Config{Routes: map[string]LimitConfig{"/health": {}}}); err == nil {
+		t.Fatalf("expected an error for an invalid limit")
+	}
+
+	if _, ok := registry.Limiter("/health"); !ok {
+		t.Fatalf("expected the limits to be kept on a reload error")
+	}
+}
+
+func TestRegistryHeaders(t *testing.T) {
+	if _, err := NewRegistry(Config{Routes: map[string]LimitConfig{
+		"/api/*": {Max: 1, Headers: map[string][]string{"X-Api-Key": nil}},
+	}}); err == nil {
+		t.Fatalf("expected an error for a header without values")
+	}
+
+	registry, err := NewRegistry(Config{Routes: map[string]LimitConfig{
+		"/api/*": {Max: 1, IPLookups: []string{"RemoteAddr"}, Headers: map[string][]string{"X-Api-Key": {"a", "b"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := iris.New()
+	app.Get("/api/items", func(ctx iris.Context) {})
+	registry.Attach(app)
+	if err = app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(apiKey string) int {
+		req := httptest.NewRequest("GET", "/api/items", nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	for _, tt := range []struct {
+		apiKey   string
+		expected []int
+	}{
+		{"a", []int{http.StatusOK, http.StatusTooManyRequests}},
+		{"b", []int{http.StatusOK, http.StatusTooManyRequests}},
+		{"c", []int{http.StatusOK, http.StatusOK}}, // not listed, not limited.
+	} {
+		for i, expected := range tt.expected {
+			if code := serve(tt.apiKey); code != expected {
+				t.Fatalf("%s: request %d: expected status code %d but got %d", tt.apiKey, i+1, expected, code)
+			}
+		}
+	}
+}
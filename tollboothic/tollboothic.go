@@ -2,11 +2,18 @@
 package tollboothic
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
+
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/context"
 
 	"github.com/didip/tollbooth/v6"
+	"github.com/didip/tollbooth/v6/errors"
 	"github.com/didip/tollbooth/v6/limiter"
+
+	"github.com/iris-contrib/middleware/ratelimit"
 )
 
 func init() {
@@ -15,19 +22,129 @@ func init() {
 
 // LimitHandler is a middleware that performs
 // rate-limiting given a "limiter" configuration.
+// The optional "options" configure Iris-aware lookup keys (see KeyParts),
+// the rejection of the limited requests and the observers of its decisions.
 //
 // Read more at: https://github.com/didip/tollbooth.
-func LimitHandler(l *limiter.Limiter) iris.Handler {
+func LimitHandler(l *limiter.Limiter, options ...Option) iris.Handler {
+	opts := applyOptions(options)
+
 	return func(ctx iris.Context) {
-		httpError := tollbooth.LimitByRequest(l, ctx.ResponseWriter(), ctx.Request())
+		var (
+			httpError *errors.HTTPError
+			key       string
+			skipped   bool
+		)
+
+		if len(opts.KeyParts) > 0 {
+			keys, ok := buildKeys(ctx, opts.KeyParts)
+			if !ok || !limitsMethod(l, ctx.Method()) {
+				ctx.Next()
+				return
+			}
+
+			ctx.Header("X-Rate-Limit-Limit", fmt.Sprintf("%.2f", l.GetMax()))
+			ctx.Header("X-Rate-Limit-Duration", "1")
+
+			httpError = tollbooth.LimitByKeys(l, keys)
+			key = strings.Join(keys, "|")
+		} else {
+			httpError, key, skipped = limitByRequest(l, ctx.ResponseWriter(), ctx.Request())
+		}
+
+		if len(opts.Observers) > 0 && !skipped {
+			ratelimit.Notify(opts.Observers, ctx, Event{
+				Policy:  opts.PolicyName,
+				Key:     key,
+				Limited: httpError != nil,
+			})
+		}
+
 		if httpError != nil {
-			ctx.ContentType(l.GetMessageContentType())
-			ctx.StatusCode(httpError.StatusCode)
-			ctx.WriteString(httpError.Message)
-			ctx.StopExecution()
+			ctx.Values().Set(errorContextKey, httpError)
+			reject(ctx, l, opts, httpError)
 			return
 		}
 
 		ctx.Next()
 	}
 }
+
+const errorContextKey = "iris.tollboothic.error"
+
+// GetError returns the error of a limited request, e.g. inside
+// a RejectHandler or an OnErrorCode handler.
+func GetError(ctx iris.Context) *errors.HTTPError {
+	if httpError, ok := ctx.Values().Get(errorContextKey).(*errors.HTTPError); ok {
+		return httpError
+	}
+
+	return nil
+}
+
+func reject(ctx iris.Context, l *limiter.Limiter, opts Options, httpError *errors.HTTPError) {
+	l.ExecOnLimitReached(ctx.ResponseWriter(), ctx.Request())
+	if l.GetOverrideDefaultResponseWriter() {
+		// The limiter's OnLimitReached has written the response.
+		ctx.StopExecution()
+		return
+	}
+
+	if opts.RejectHandler != nil {
+		ctx.StopExecution()
+		opts.RejectHandler(ctx)
+		return
+	}
+
+	if opts.FireErrorCode {
+		ctx.StopWithStatus(httpError.StatusCode)
+		return
+	}
+
+	ctx.ContentType(l.GetMessageContentType())
+	ctx.StatusCode(httpError.StatusCode)
+	ctx.WriteString(httpError.Message)
+	ctx.StopExecution()
+}
+
+func limitsMethod(l *limiter.Limiter, method string) bool {
+	methods := l.GetMethods()
+	if len(methods) == 0 {
+		return true
+	}
+
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// limitByRequest is tollbooth.LimitByRequest which also reports the key of the request,
+// the limited one or the last one of an allowed request joined by "|",
+// and whether the limiter skipped the request.
+func limitByRequest(l *limiter.Limiter, w http.ResponseWriter, r *http.Request) (*errors.HTTPError, string, bool) {
+	header := w.Header()
+	header.Add("X-Rate-Limit-Limit", fmt.Sprintf("%.2f", l.GetMax()))
+	header.Add("X-Rate-Limit-Duration", "1")
+	if xForwardedFor := r.Header.Get("X-Forwarded-For"); strings.TrimSpace(xForwardedFor) != "" {
+		header.Add("X-Rate-Limit-Request-Forwarded-For", xForwardedFor)
+	}
+	header.Add("X-Rate-Limit-Request-Remote-Addr", r.RemoteAddr)
+
+	if tollbooth.ShouldSkipLimiter(l, r) {
+		return nil, "", true
+	}
+
+	var key string
+	for _, keys := range tollbooth.BuildKeys(l, r) {
+		key = strings.Join(keys, "|")
+		if httpError := tollbooth.LimitByKeys(l, keys); httpError != nil {
+			return httpError, key, false
+		}
+	}
+
+	return nil, key, false
+}
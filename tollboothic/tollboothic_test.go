@@ -0,0 +1,143 @@
+package tollboothic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/didip/tollbooth/v6"
+)
+
+type decisionRecorder []string
+
+func (r *decisionRecorder) RecordDecision(route, policy, key, decision string) {
+	*r = append(*r, strings.Join([]string{route, policy, key, decision}, " "))
+}
+
+func TestLimitHandlerObservers(t *testing.T) {
+	var recorded decisionRecorder
+
+	var events []Event
+	record := ObserverFunc(func(ctx iris.Context, event Event) {
+		events = append(events, event)
+	})
+
+	app := iris.New()
+	app.Get("/", LimitHandler(tollbooth.NewLimiter(1, nil), PolicyName("index"), Observers(Record(&recorded), record)), func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []int
+	for range 2 {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		app.ServeHTTP(res, req)
+		codes = append(codes, res.Code)
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status codes: %v", codes)
+	}
+
+	if len(events) != 2 || events[0].Limited || !events[1].Limited {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if e := events[1]; e.Route != "GET/" || e.Policy != "index" || e.Key != "192.0.2.1|/|" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	expected := decisionRecorder{"GET/ index 192.0.2.1|/| allowed", "GET/ index 192.0.2.1|/| denied"}
+	if !reflect.DeepEqual(recorded, expected) {
+		t.Errorf("expected decisions %q but got %q", expected, recorded)
+	}
+}
+
+func TestLimitHandlerObserversSkipped(t *testing.T) {
+	var events []Event
+	record := ObserverFunc(func(ctx iris.Context, event Event) {
+		events = append(events, event)
+	})
+
+	app := iris.New()
+	limiter := tollbooth.NewLimiter(1, nil).SetMethods([]string{"POST"})
+	app.Get("/observed", LimitHandler(limiter, Observers(record)), func(ctx iris.Context) {})
+	app.Get("/", LimitHandler(limiter), func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) http.Header {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		app.ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected status code %d but got %d", path, http.StatusOK, res.Code)
+		}
+		return res.Header()
+	}
+
+	expected, observed := serve("/"), serve("/observed")
+	if observed.Get("X-Rate-Limit-Limit") == "" || !reflect.DeepEqual(observed, expected) {
+		t.Fatalf("expected the headers %v of a skipped request but got %v", expected, observed)
+	}
+
+	if len(events) != 0 {
+		t.Fatalf("expected no events for a skipped request but got %+v", events)
+	}
+}
+
+func TestLimitHandlerKeyParts(t *testing.T) {
+	app := iris.New()
+	app.OnErrorCode(http.StatusTooManyRequests, func(ctx iris.Context) {
+		ctx.WriteString("slow down: " + GetError(ctx).Message)
+	})
+
+	tenants := app.Party("/tenants/{tenant}")
+	tenants.Get("/fire", LimitHandler(tollbooth.NewLimiter(1, nil), KeyParts(ByParam("tenant")), FireErrorCode()), func(ctx iris.Context) {})
+	tenants.Get("/reject", LimitHandler(tollbooth.NewLimiter(1, nil), KeyParts(ByParam("tenant"), ByHeader("X-User")), RejectHandler(func(ctx iris.Context) {
+		ctx.StopWithJSON(http.StatusTooManyRequests, iris.Map{"tenant": ctx.Params().Get("tenant")})
+	})), func(ctx iris.Context) {})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path, user string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	serve("/tenants/a/fire", "")
+	if res := serve("/tenants/b/fire", ""); res.Code != http.StatusOK {
+		t.Fatalf("expected tenants to be limited separately but got %d", res.Code)
+	}
+
+	res := serve("/tenants/a/fire", "")
+	if res.Code != http.StatusTooManyRequests || res.Body.String() != "slow down: You have reached maximum request limit." {
+		t.Fatalf("expected the error code handler to fire but got %d: %s", res.Code, res.Body.String())
+	}
+
+	for range 3 {
+		if res = serve("/tenants/a/reject", ""); res.Code != http.StatusOK {
+			t.Fatalf("expected requests without a user to be skipped but got %d", res.Code)
+		}
+	}
+
+	serve("/tenants/a/reject", "u1")
+	res = serve("/tenants/a/reject", "u1")
+	if res.Code != http.StatusTooManyRequests || strings.TrimSpace(res.Body.String()) != `{"tenant":"a"}` {
+		t.Fatalf("expected the reject handler to be called but got %d: %s", res.Code, res.Body.String())
+	}
+}